@@ -0,0 +1,143 @@
+//go:build linux
+
+package webview
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+// loadedWebKit is the Context shared by all apps. The library is loaded once
+// per process, since GTK can't be initialized again.
+var loadedWebKit webkitgtk.Context = nil
+
+// defaultApp hosts the webviews created with Create.
+var defaultApp *app = nil
+
+type app struct {
+	options AppOptions
+	webkit  webkitgtk.Context
+
+	mutex   sync.Mutex
+	windows []*webview
+	running bool
+}
+
+// NewApp creates an app using the provided options. The error wraps
+// ErrLibraryNotFound, ErrUnsupportedVersion or ErrDisplayUnavailable when
+// WebKitGTK can't be used.
+func NewApp(options AppOptions) (App, error) {
+	a, err := loadApp(options)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func loadApp(options AppOptions) (*app, error) {
+	if loadedWebKit == nil {
+		ctx, err := loadWebKit(options.WebKitLibrary)
+		if err != nil {
+			return nil, err
+		}
+		loadedWebKit = ctx
+	}
+
+	return newApp(loadedWebKit, options)
+}
+
+// newApp creates an app that makes all native calls through webkit, which
+// may be a webkitgtktest.Recorder in tests.
+func newApp(webkit webkitgtk.Context, options AppOptions) (*app, error) {
+	if !webkit.GtkInitCheck() {
+		return nil, fmt.Errorf("%w: failed to initialize GTK", ErrDisplayUnavailable)
+	}
+
+	return &app{
+		options: options,
+		webkit:  webkit,
+	}, nil
+}
+
+func (a *app) NewWindow(options WebViewOptions) (WebView, error) {
+	w, err := a.newWindow(options)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (a *app) newWindow(options WebViewOptions) (*webview, error) {
+	w, err := newWebView(a, options)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.windows = append(a.windows, w)
+	return w, nil
+}
+
+func (a *app) Windows() []WebView {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	windows := make([]WebView, len(a.windows))
+	for i, w := range a.windows {
+		windows[i] = w
+	}
+	return windows
+}
+
+func (a *app) Run() {
+	a.mutex.Lock()
+	a.running = true
+	a.mutex.Unlock()
+
+	a.webkit.GtkMain()
+
+	a.mutex.Lock()
+	a.running = false
+	a.mutex.Unlock()
+}
+
+func (a *app) Quit() {
+	a.mutex.Lock()
+	running := a.running
+	a.mutex.Unlock()
+
+	// Quitting a main loop that isn't running is an error in GTK 3.
+	if running {
+		a.webkit.GtkMainQuit()
+	}
+}
+
+func (a *app) Dispatch(f func()) {
+	a.webkit.GIdleAddFull(webkitgtk.G_PRIORITY_HIGH_IDLE, func(userData uintptr) bool {
+		f()
+		return webkitgtk.G_SOURCE_REMOVE
+	}, webkitgtk.NULLPTR, nil)
+}
+
+// windowClosed is called once the window of w has been destroyed, either by
+// the user or by Destroy.
+func (a *app) windowClosed(w *webview) {
+	forgetSchemes(w)
+
+	a.mutex.Lock()
+	for i, window := range a.windows {
+		if window == w {
+			a.windows = append(a.windows[:i], a.windows[i+1:]...)
+			break
+		}
+	}
+	last := len(a.windows) == 0
+	a.mutex.Unlock()
+
+	if last && a.options.QuitPolicy == QuitOnLastWindowClosed {
+		a.Quit()
+	}
+}
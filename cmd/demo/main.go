@@ -8,11 +8,11 @@ import (
 )
 
 func main() {
-	w := webview.NewWithOptions(webview.WebViewOptions{
+	w, err := webview.Create(webview.WebViewOptions{
 		Debug: true,
 	})
-	if w == nil {
-		log.Fatalln("Failed to load webview.")
+	if err != nil {
+		log.Fatalf("Failed to load webview: %v", err)
 	}
 	defer w.Destroy()
 	w.SetTitle("Demo Example")
@@ -0,0 +1,139 @@
+// Command webview-tsgen writes TypeScript declarations for the functions a Go
+// package binds to a webview.
+//
+// Usage:
+//
+//	webview-tsgen [-o file] package function
+//
+// The function must be exported from a package other than main and have the
+// signature func(webview.WebView), binding functions with Bind and
+// BindObject. webview-tsgen builds a program in the module of the current
+// directory that calls it with a webviewtest.WebView, and passes the bindings
+// to tsgen.Generate. For example:
+//
+//	//go:generate go run github.com/mekkanized/go-webview/cmd/webview-tsgen -o ../web/src/bindings.d.ts . RegisterBindings
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/token"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var program = template.Must(template.New("main").Parse(`// Code generated by webview-tsgen. DO NOT EDIT.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	bindings {{printf "%q" .Package}}
+	"github.com/mekkanized/go-webview/tsgen"
+	"github.com/mekkanized/go-webview/webviewtest"
+)
+
+func main() {
+	w := webviewtest.New()
+	defer w.Destroy()
+	bindings.{{.Function}}(w)
+
+	f, err := os.Create(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := tsgen.Generate(f, w.Bindings()); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+`))
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("webview-tsgen: ")
+
+	output := flag.String("o", "", "write the declarations to `file` instead of standard output")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: webview-tsgen [-o file] package function\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkg, function := flag.Arg(0), flag.Arg(1)
+	if !token.IsIdentifier(function) || !token.IsExported(function) {
+		log.Fatalf("%s is not an exported function name", function)
+	}
+
+	if err := run(pkg, function, *output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(pkg, function, output string) error {
+	// Resolve relative package paths, which the generated program can't
+	// import.
+	out, err := exec.Command("go", "list", "-f", "{{.Name}} {{.ImportPath}}", pkg).Output()
+	if err != nil {
+		return fmt.Errorf("failed to find package %s: %w", pkg, commandError(err))
+	}
+	name, importPath, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	if name == "main" {
+		return fmt.Errorf("package %s is a main package and can't be imported", pkg)
+	}
+
+	// The program is built in the current directory so that it belongs to the
+	// module of the package.
+	dir, err := os.MkdirTemp(".", "webview-tsgen")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var src bytes.Buffer
+	if err := program.Execute(&src, struct{ Package, Function string }{importPath, function}); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	declarations := filepath.Join(dir, "bindings.d.ts")
+	cmd := exec.Command("go", "run", "./"+filepath.ToSlash(dir), declarations)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run %s.%s: %w", importPath, function, err)
+	}
+
+	ts, err := os.ReadFile(declarations)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(ts)
+		return err
+	}
+	return os.WriteFile(output, ts, 0o644)
+}
+
+// commandError adds the standard error of a failed command to err.
+func commandError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w\n%s", err, bytes.TrimSpace(exitErr.Stderr))
+	}
+	return err
+}
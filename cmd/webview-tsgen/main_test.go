@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs a program")
+	}
+
+	output := filepath.Join(t.TempDir(), "bindings.d.ts")
+	if err := run("./testdata/example", "RegisterBindings", output); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join("testdata", "example.d.ts")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, rerun with -update if the change is intended:\n%s", path, got)
+	}
+}
+
+func TestRunMainPackage(t *testing.T) {
+	err := run(".", "RegisterBindings", "")
+	if err == nil || !strings.Contains(err.Error(), "is a main package") {
+		t.Errorf("run(main package) = %v, want an error", err)
+	}
+}
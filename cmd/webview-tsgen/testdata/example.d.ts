@@ -0,0 +1,21 @@
+// Code generated by go-webview/tsgen. DO NOT EDIT.
+
+export interface Todo {
+	id: number;
+	title: string;
+	done?: boolean;
+	tags: string[] | null;
+}
+
+declare global {
+	interface Window {
+		greet(arg0: string): Promise<string>;
+		todos: {
+			add(arg0: string): Promise<Todo | null>;
+			list(): Promise<Todo[] | null>;
+			remove(arg0: number): Promise<void>;
+		};
+	}
+}
+
+export {};
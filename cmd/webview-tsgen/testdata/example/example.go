@@ -0,0 +1,27 @@
+// Package example binds the functions of the webview-tsgen golden test.
+package example
+
+import (
+	"context"
+
+	webview "github.com/mekkanized/go-webview"
+)
+
+type Todo struct {
+	ID    int      `json:"id"`
+	Title string   `json:"title"`
+	Done  bool     `json:"done,omitempty"`
+	Tags  []string `json:"tags"`
+}
+
+type Todos struct{}
+
+func (Todos) List(ctx context.Context) ([]Todo, error) { return nil, nil }
+func (Todos) Add(title string) (*Todo, error)          { return nil, nil }
+func (Todos) Remove(id int) error                      { return nil }
+
+// RegisterBindings binds a function and the methods of Todos.
+func RegisterBindings(w webview.WebView) {
+	w.Bind("greet", func(name string) string { return "Hello, " + name })
+	w.BindObject("todos", Todos{})
+}
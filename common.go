@@ -1,9 +1,323 @@
 package webview
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
 	"unsafe"
+
+	"github.com/mekkanized/go-webview/internal/bridge"
+)
+
+var (
+	// ErrNotSupported is returned by features the current platform backend
+	// does not implement.
+	ErrNotSupported = errors.New("webview: not supported on this platform")
+
+	// ErrLibraryNotFound is returned by Create when the native webview library
+	// is not installed.
+	ErrLibraryNotFound = errors.New("webview: library not found")
+
+	// ErrUnsupportedVersion is returned by Create when the native webview
+	// library is too old or lacks required functions.
+	ErrUnsupportedVersion = errors.New("webview: unsupported library version")
+
+	// ErrDisplayUnavailable is returned by Create when there is no display to
+	// open a window on.
+	ErrDisplayUnavailable = errors.New("webview: display unavailable")
+
+	// ErrDestroyed is returned by methods of a webview that has been
+	// destroyed, or whose window has been closed.
+	ErrDestroyed = errors.New("webview: destroyed")
+)
+
+// JSError is returned by EvalResult when the evaluated JavaScript throws.
+type JSError struct {
+	Message string
+}
+
+func (e *JSError) Error() string {
+	return "javascript: " + e.Message
+}
+
+// RPCError is the JSON-RPC 2.0 error object the promises of bound functions
+// are rejected with. Bound functions can return one, possibly wrapped, to
+// choose the code and data JavaScript receives. Other errors are reported
+// with the code RPCInternalError and their message.
+type RPCError = bridge.RPCError
+
+// Error codes defined by JSON-RPC 2.0. Applications should use codes outside
+// of the range from -32768 to -32000.
+const (
+	RPCParseError     = bridge.ParseError
+	RPCInvalidRequest = bridge.InvalidRequest
+	RPCMethodNotFound = bridge.MethodNotFound
+	RPCInvalidParams  = bridge.InvalidParams
+	RPCInternalError  = bridge.InternalError
 )
 
+// NavigationEventType identifies the stage of a navigation.
+type NavigationEventType int
+
+const (
+	// NavigationStarted is reported when a new page starts loading.
+	NavigationStarted NavigationEventType = iota
+	// NavigationRedirected is reported when the request was redirected. URL
+	// is the new location.
+	NavigationRedirected
+	// NavigationCommitted is reported once the first data of the page has
+	// been received. StatusCode is available from here on.
+	NavigationCommitted
+	// NavigationFinished is reported when the page has loaded, and also after
+	// a failed navigation.
+	NavigationFinished
+	// NavigationFailed is reported when the page could not be loaded. Err
+	// describes the failure.
+	NavigationFailed
+)
+
+// NavigationEvent reports the progress of a navigation of the main frame.
+type NavigationEvent struct {
+	Type NavigationEventType
+	URL  string
+
+	// StatusCode is the HTTP status of the page, or 0 if it's not known yet or
+	// the page wasn't loaded over HTTP.
+	StatusCode int
+
+	// Err is set for NavigationFailed, to a *NavigationError on Linux.
+	Err error
+
+	// ErrorHTML can be set by the callbacks of a NavigationFailed event to
+	// show a custom error page instead of the default one.
+	ErrorHTML string
+}
+
+// NavigationError is the error of a failed navigation, as reported by the
+// platform.
+type NavigationError struct {
+	// Domain and Code identify the error. On Linux they are the domain and
+	// code of the GError reported by WebKitGTK, e.g. "WebKitNetworkError" and
+	// 300 for a network failure.
+	Domain string
+	Code   int
+
+	Message string
+}
+
+func (e *NavigationError) Error() string {
+	return e.Message
+}
+
+// PolicyDecisionType identifies what a policy decision is about.
+type PolicyDecisionType int
+
+const (
+	// DecisionNavigation is made before a frame navigates to URL.
+	DecisionNavigation PolicyDecisionType = iota
+	// DecisionNewWindow is made when a link or window.open() asks for URL to
+	// be opened in a new window.
+	DecisionNewWindow
+	// DecisionResponse is made when the response for URL has been received,
+	// before it is displayed.
+	DecisionResponse
+)
+
+// PolicyDecision describes a request the navigation policy decides on.
+type PolicyDecision struct {
+	Type PolicyDecisionType
+	URL  string
+
+	// UserGesture reports whether a navigation or new window was triggered
+	// by the user, e.g. by clicking a link.
+	UserGesture bool
+
+	// MIMEType and StatusCode describe the response of a DecisionResponse.
+	MIMEType   string
+	StatusCode int
+
+	// MainFrame reports whether a DecisionResponse is for the document of
+	// the main frame rather than of an iframe. It is false for the other
+	// decisions, which WebKit also makes for iframes without telling them
+	// apart, so policies that must leave iframes alone should act on
+	// responses. On Linux it requires WebKitGTK 2.40 and is always true for
+	// responses on older versions.
+	MainFrame bool
+}
+
+// Policy is the decision returned by a navigation policy.
+type Policy int
+
+const (
+	// PolicyAllow lets the request proceed. New windows are opened in the
+	// webview itself, and responses get the default handling of the
+	// backend, which on Linux downloads what cannot be displayed.
+	PolicyAllow Policy = iota
+	// PolicyIgnore cancels the request.
+	PolicyIgnore
+	// PolicyOpenExternal cancels the request and opens URL in the default
+	// application of the system, usually the browser.
+	PolicyOpenExternal
+)
+
+// SizeConstraints limits the sizes a user can resize a window to. Zero
+// fields are unconstrained.
+type SizeConstraints struct {
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+
+	// MinAspect and MaxAspect bound the ratio of width to height. Setting
+	// only one of them, or both to the same value, keeps the ratio fixed.
+	MinAspect float64
+	MaxAspect float64
+
+	// WidthInc and HeightInc are the steps the window is resized in,
+	// starting from the minimum size.
+	WidthInc  int
+	HeightInc int
+}
+
+// WindowEventType identifies a change of the native window.
+type WindowEventType int
+
+const (
+	// WindowCloseRequested is reported when the user asks to close the
+	// window. Callbacks can set Cancel to keep it open.
+	WindowCloseRequested WindowEventType = iota
+	// WindowResized is reported when the size of the window changed.
+	WindowResized
+	// WindowMoved is reported when the window was moved, on platforms that
+	// report the position.
+	WindowMoved
+	// WindowFocused is reported when the window became active.
+	WindowFocused
+	// WindowBlurred is reported when the window is no longer active.
+	WindowBlurred
+	// WindowStateChanged is reported when the window was maximized,
+	// minimized, made fullscreen or restored.
+	WindowStateChanged
+)
+
+// WindowState is a set of window state flags.
+type WindowState uint
+
+const (
+	WindowStateMaximized WindowState = 1 << iota
+	WindowStateMinimized
+	WindowStateFullscreen
+)
+
+// WindowEvent reports a change of the native window.
+type WindowEvent struct {
+	Type WindowEventType
+
+	// X, Y, Width and Height are the position and size of the window after a
+	// WindowMoved or WindowResized event.
+	X      int
+	Y      int
+	Width  int
+	Height int
+
+	// State is the state of the window after a WindowStateChanged event.
+	State WindowState
+
+	// Cancel can be set by the callbacks of a WindowCloseRequested event to
+	// keep the window open, e.g. to ask about unsaved changes first.
+	Cancel bool
+}
+
+// CookieStorage is the file format cookies are persisted in.
+type CookieStorage int
+
+const (
+	CookieStorageSQLite CookieStorage = iota
+	CookieStorageText
+)
+
+// CookieAcceptPolicy controls which cookies a webview stores.
+type CookieAcceptPolicy int
+
+const (
+	CookieAcceptAlways CookieAcceptPolicy = iota
+	CookieAcceptNever
+	// CookieAcceptNoThirdParty only accepts cookies set by the site of the
+	// page being loaded.
+	CookieAcceptNoThirdParty
+)
+
+// CookieManager reads and modifies the cookies of a webview. Its methods can
+// be called from any goroutine, but those taking a context block until the
+// main loop has run the operation, like EvalResult, so they must not be called
+// from the UI thread, including bound functions without a context and On
+// handlers. They fail with ErrDestroyed once the webview has been destroyed,
+// and the other methods then do nothing.
+type CookieManager interface {
+	// Cookies returns the cookies that would be sent with a request to url.
+	Cookies(ctx context.Context, url string) ([]*http.Cookie, error)
+
+	// SetCookie stores cookie as if it was received in a response from url.
+	// Its Domain defaults to the host of url and its Path to "/".
+	SetCookie(ctx context.Context, url string, cookie *http.Cookie) error
+
+	// DeleteCookie removes the cookie with the name, domain and path of
+	// cookie, which default as for SetCookie.
+	DeleteCookie(ctx context.Context, url string, cookie *http.Cookie) error
+
+	// Clear removes all cookies.
+	Clear(ctx context.Context) error
+
+	// SetPersistentStorage stores the cookies in filename, which is created
+	// if necessary, instead of the default location.
+	SetPersistentStorage(filename string, storage CookieStorage)
+
+	// SetAcceptPolicy sets which cookies are stored.
+	SetAcceptPolicy(policy CookieAcceptPolicy)
+}
+
+// UserScriptInjectionTime is when a user script runs in a page.
+type UserScriptInjectionTime int
+
+const (
+	// UserScriptAtDocumentStart runs the script before any other script of
+	// the page.
+	UserScriptAtDocumentStart UserScriptInjectionTime = iota
+	// UserScriptAtDocumentEnd runs the script once the document has been
+	// parsed, before subresources like images have loaded.
+	UserScriptAtDocumentEnd
+)
+
+// UserScriptOptions describes a script added with WebView.AddUserScript.
+type UserScriptOptions struct {
+	// Source is the JavaScript code of the script.
+	Source string
+
+	// InjectionTime is when the script runs, at document start by default.
+	InjectionTime UserScriptInjectionTime
+
+	// AllFrames injects the script into every frame of a page instead of only
+	// the top frame.
+	AllFrames bool
+
+	// AllowList restricts the script to the frames whose URL matches one of
+	// its patterns, and BlockList excludes the frames matching one of its
+	// patterns. Patterns have the form scheme://host/path, where * matches any
+	// scheme, a host may start with *. to match its subdomains, and * in the
+	// path matches any characters, e.g. "https://*.example.com/admin/*".
+	// Only supported on Linux.
+	AllowList []string
+	BlockList []string
+}
+
+// UserScript is a script added with WebView.AddUserScript.
+type UserScript interface {
+	// Remove stops injecting the script into new pages. Pages that are
+	// already loaded are not affected.
+	Remove()
+}
+
 // WebView is the interface for the webview.
 type WebView interface {
 	// Run runs the main loop until it's terminated. After this function exits -
@@ -16,10 +330,12 @@ type WebView interface {
 
 	// Dispatch posts a function to be executed on the main thread. You normally
 	// do not need to call this function, unless you want to tweak the native
-	// window.
+	// window. Functions are dropped once the webview has been destroyed.
 	Dispatch(f func())
 
-	// Destroy destroys a webview and closes the native window.
+	// Destroy destroys a webview and closes the native window. It releases the
+	// bindings, injected scripts and native resources of the webview, and may
+	// be called more than once.
 	Destroy()
 
 	// Window returns a native window handle pointer. When using GTK backend the
@@ -34,6 +350,49 @@ type WebView interface {
 	// SetSize updates native window size. See Hint constants.
 	SetSize(w int, h int, hint Hint)
 
+	// SetSizeConstraints replaces the size constraints of the window,
+	// including the bounds set with HintMin and HintMax, with c. GTK 4 leaves
+	// the constraints to the window manager and only applies the minimum
+	// size, the maximum size and aspect ratios have no effect there.
+	SetSizeConstraints(c SizeConstraints)
+
+	// Size returns the size of the native window, without decorations.
+	Size() (width int, height int)
+
+	// SetPosition moves the native window so that the top-left corner of its
+	// frame is at x, y in screen coordinates. Not every platform lets windows
+	// position themselves, e.g. Wayland and GTK 4 don't, and SetPosition has
+	// no effect there.
+	SetPosition(x int, y int)
+
+	// Position returns the position of the top-left corner of the window
+	// frame in screen coordinates, as reported by the window manager, so it
+	// reflects moves by the user and may differ from the one passed to
+	// SetPosition. It is 0, 0 where windows cannot position themselves, and on
+	// platforms that don't implement it yet.
+	Position() (x int, y int)
+
+	// Maximize maximizes the native window.
+	Maximize()
+
+	// Unmaximize restores the native window from being maximized.
+	Unmaximize()
+
+	// Minimize minimizes (iconifies) the native window.
+	Minimize()
+
+	// SetFullscreen makes the native window cover the whole screen, or
+	// restores it.
+	SetFullscreen(fullscreen bool)
+
+	// SetAlwaysOnTop keeps the native window above other windows. This is a
+	// hint the window manager may ignore, and it isn't supported by GTK 4.
+	SetAlwaysOnTop(onTop bool)
+
+	// SetDecorated shows or hides the title bar and borders of the native
+	// window.
+	SetDecorated(decorated bool)
+
 	// Navigate navigates webview to the given URL. URL may be a data URI, i.e.
 	// "data:text/text,<html>...</html>". It is often ok not to url-encode it
 	// properly, webview will re-encode it for you.
@@ -48,27 +407,120 @@ type WebView interface {
 	// be executed. It is guaranteed that code is executed before window.onload.
 	Init(js string)
 
+	// AddUserScript injects a script into the pages loaded from now on, like
+	// Init with options to choose when and where it runs. The returned
+	// UserScript removes it.
+	AddUserScript(options UserScriptOptions) UserScript
+
 	// Eval evaluates arbitrary JavaScript code. Evaluation happens asynchronously,
 	// also the result of the expression is ignored. Use RPC bindings if you want
-	// to receive notifications about the results of the evaluation.
+	// to receive notifications about the results of the evaluation. Eval does
+	// nothing once the webview has been destroyed.
 	Eval(js string)
 
+	// EvalResult evaluates JavaScript code and waits for the value of its last
+	// expression, encoded as JSON. Values that have no JSON representation,
+	// like undefined or functions, are returned as null and promises are not
+	// awaited. Exceptions are returned as a *JSError. If ctx is done before the
+	// evaluation completes the evaluation is cancelled and ctx.Err() returned.
+	//
+	// EvalResult blocks until the main loop has run the script, so it must not
+	// be called from the UI thread. It returns ErrDestroyed once the webview
+	// has been destroyed.
+	EvalResult(ctx context.Context, js string) (json.RawMessage, error)
+
 	// Bind binds a callback function so that it will appear under the given name
-	// as a global JavaScript function. Internally it uses webview_init().
-	// Callback receives a request string and a user-provided argument pointer.
-	// Request string is a JSON array of all the arguments passed to the
-	// JavaScript function.
+	// as a global JavaScript function. Calling it posts a JSON-RPC 2.0 request
+	// with the arguments through window._rpc, they are unmarshalled into the
+	// parameters of f, and the response settles the returned promise through
+	// window._rpc.receive.
 	//
 	// f must be a function
 	// f must return either value and error or just error
+	//
+	// The promise returned to JavaScript is resolved with the result of f, or rejected with the {code, message,
+	// data} error object if f fails, see RPCError. Calls to names that are not
+	// bound are rejected with RPCMethodNotFound, calls with arguments that do
+	// not match f with RPCInvalidParams, and calls of f that panic with
+	// RPCInternalError.
+	//
+	// Functions are called on the main thread, unless their first argument is
+	// a context.Context. Those run on their own goroutine and the promise is
+	// settled when they return. Their context is cancelled when the page
+	// navigates away or when JavaScript calls cancel() on the returned promise.
 	Bind(name string, f interface{}) error
+
+	// BindObject binds every exported method of obj as a function of the
+	// global JavaScript object window[namespace], which is created if needed.
+	// Method names are converted to lower camel case, so obj.GetUser is called
+	// as window[namespace].getUser(). The methods behave like functions bound
+	// with Bind and are subject to the same restrictions. Nothing is bound if
+	// one of them can't be, or if obj is nil.
+	BindObject(namespace string, obj interface{}) error
+
+	// Unbind removes the function bound with Bind, or the namespace object
+	// bound with BindObject, under the given name. The JavaScript global is
+	// deleted from the current page and no longer defined in new pages. Calls
+	// still running complete, later ones are rejected with RPCMethodNotFound.
+	// Binding a name that is already bound replaces the previous binding.
+	Unbind(name string) error
+
+	// Emit dispatches an event to the JavaScript listeners registered with
+	// window.webview.on(event, fn). The payload is encoded as JSON and passed
+	// as the listener's only argument. It is safe to call this function from a
+	// background thread. Events emitted once the webview has been destroyed
+	// are dropped.
+	Emit(event string, payload interface{}) error
+
+	// On subscribes f to the events JavaScript sends with
+	// window.webview.emit(event, payload). f is called on the main thread with
+	// the JSON encoded payload. The returned function removes the subscription.
+	On(event string, f func(payload json.RawMessage)) (off func())
+
+	// OnNavigation subscribes f to the navigation events of the main frame.
+	// f is called on the main thread. The returned function removes the
+	// subscription.
+	OnNavigation(f func(e *NavigationEvent)) (off func())
+
+	// Cookies returns the cookie manager of the webview.
+	Cookies() CookieManager
+
+	// OnWindowEvent subscribes f to the events of the native window. f is
+	// called on the main thread. The returned function removes the
+	// subscription.
+	OnWindowEvent(f func(e *WindowEvent)) (off func())
+
+	// SetNavigationPolicy sets the function that decides whether navigations,
+	// new windows and responses are allowed. f is called on the main thread
+	// and must return quickly. A nil f restores the default behavior of the
+	// platform.
+	SetNavigationPolicy(f func(d *PolicyDecision) Policy)
+
+	// RegisterScheme serves every request for the given custom URI scheme (e.g.
+	// "app") with handler. The scheme is treated as secure and CORS enabled, so
+	// pages loaded from it can use fetch() and ES modules with relative paths.
+	// Use http.FileServer(http.FS(fsys)) to serve an embed.FS. The handler runs
+	// on its own goroutine and its response is streamed to the page.
+	//
+	// Schemes must be registered before navigating to them. RegisterScheme
+	// returns ErrDestroyed once the webview has been destroyed.
+	RegisterScheme(scheme string, handler http.Handler) error
 }
 
+// WindowOptions configure the window created for a webview. They are
+// ignored if WebViewOptions.Window is set.
 type WindowOptions struct {
-	Title  string
+	Title string
+	// Width and Height are the initial size of the window, if both are set.
 	Width  uint
 	Height uint
+	// IconId is the ID of an icon resource of the executable on Windows.
 	IconId uint
+	// Icon is an image, such as a PNG, used as the window icon on Linux with
+	// GTK 3. It is not supported on Windows, which uses IconId instead. An
+	// image that cannot be decoded is ignored.
+	Icon []byte
+	// Center places the window in the middle of the screen.
 	Center bool
 }
 
@@ -76,13 +528,69 @@ type WebViewOptions struct {
 	Window unsafe.Pointer
 	Debug  bool
 
+	// DataPath is the directory the website data, such as cookies,
+	// localStorage, IndexedDB and the cache, is stored in. It defaults to a
+	// location shared with other webviews of the user.
 	DataPath string
 
+	// Ephemeral keeps all website data in memory, so that it is discarded
+	// when the webview is destroyed. It takes precedence over DataPath.
+	Ephemeral bool
+
+	// WebKitLibrary is the soname or path of the WebKitGTK library to load on
+	// Linux. It defaults to the WEBVIEW_WEBKITGTK_LIBRARY environment variable
+	// or, if that is unset, the first of the supported libraries installed.
+	// The library is loaded once per process, so it only applies to the first
+	// webview or app created.
+	WebKitLibrary string
+
 	AutoFocus bool
 
 	WindowOptions WindowOptions
 }
 
+// QuitPolicy controls when an App stops its main loop.
+type QuitPolicy int
+
+const (
+	// QuitOnLastWindowClosed stops the main loop once the last window of the
+	// app has been closed or destroyed.
+	QuitOnLastWindowClosed QuitPolicy = iota
+	// QuitExplicitly keeps the main loop running until Quit is called.
+	QuitExplicitly
+)
+
+type AppOptions struct {
+	QuitPolicy QuitPolicy
+
+	// WebKitLibrary is the soname or path of the WebKitGTK library to load on
+	// Linux, see WebViewOptions.WebKitLibrary.
+	WebKitLibrary string
+}
+
+// App owns the main loop and hosts any number of windows, each with its own
+// webview.
+type App interface {
+	// NewWindow creates a new window with a webview. Must be called from the
+	// UI thread. The Run and Terminate methods of the window run and stop the
+	// main loop of the app.
+	NewWindow(options WebViewOptions) (WebView, error)
+
+	// Windows returns the windows that haven't been closed or destroyed yet.
+	Windows() []WebView
+
+	// Run runs the main loop until Quit is called or, depending on the
+	// QuitPolicy, the last window is closed.
+	Run()
+
+	// Quit stops the main loop. It is safe to call this function from a
+	// background thread.
+	Quit()
+
+	// Dispatch posts a function to be executed on the main thread.
+	Dispatch(f func())
+}
+
 // New calls NewWindow to create a new window and a new webview instance. If debug
 // is non-zero - developer tools will be enabled (if the platform supports them).
 func New(debug bool) WebView {
@@ -90,3 +598,13 @@ func New(debug bool) WebView {
 		Debug: debug,
 	})
 }
+
+// NewWithOptions creates a new webview using the provided options. It panics if
+// the webview can't be created, use Create to handle the error instead.
+func NewWithOptions(options WebViewOptions) WebView {
+	w, err := Create(options)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
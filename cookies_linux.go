@@ -0,0 +1,226 @@
+//go:build linux
+
+package webview
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+// cookieManager implements CookieManager with the WebKitCookieManager of the
+// network session of a webview.
+type cookieManager struct {
+	w *webview
+}
+
+func (w *webview) Cookies() CookieManager {
+	return &cookieManager{w: w}
+}
+
+func (m *cookieManager) Cookies(ctx context.Context, uri string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	err := m.call(ctx, func(manager webkitgtk.WebKitCookieManager, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback) {
+		m.w.webkit.WebKitCookieManagerGetCookies(manager, uri, cancellable, callback, webkitgtk.NULLPTR)
+	}, func(manager webkitgtk.WebKitCookieManager, res webkitgtk.GAsyncResult) error {
+		data, err := m.w.webkit.WebKitCookieManagerGetCookiesFinish(manager, res)
+		for _, d := range data {
+			cookies = append(cookies, fromSoupCookie(d))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookies: %w", err)
+	}
+	return cookies, nil
+}
+
+func (m *cookieManager) SetCookie(ctx context.Context, uri string, cookie *http.Cookie) error {
+	data, err := toSoupCookie(uri, cookie)
+	if err != nil {
+		return err
+	}
+
+	err = m.call(ctx, func(manager webkitgtk.WebKitCookieManager, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback) {
+		soupCookie := m.w.webkit.SoupCookieNew(data)
+		defer m.w.webkit.SoupCookieFree(soupCookie)
+		m.w.webkit.WebKitCookieManagerAddCookie(manager, soupCookie, cancellable, callback, webkitgtk.NULLPTR)
+	}, m.w.webkit.WebKitCookieManagerAddCookieFinish)
+	if err != nil {
+		return fmt.Errorf("failed to set cookie %s: %w", cookie.Name, err)
+	}
+	return nil
+}
+
+func (m *cookieManager) DeleteCookie(ctx context.Context, uri string, cookie *http.Cookie) error {
+	data, err := toSoupCookie(uri, cookie)
+	if err != nil {
+		return err
+	}
+
+	err = m.call(ctx, func(manager webkitgtk.WebKitCookieManager, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback) {
+		soupCookie := m.w.webkit.SoupCookieNew(data)
+		defer m.w.webkit.SoupCookieFree(soupCookie)
+		m.w.webkit.WebKitCookieManagerDeleteCookie(manager, soupCookie, cancellable, callback, webkitgtk.NULLPTR)
+	}, m.w.webkit.WebKitCookieManagerDeleteCookieFinish)
+	if err != nil {
+		return fmt.Errorf("failed to delete cookie %s: %w", cookie.Name, err)
+	}
+	return nil
+}
+
+// Clear removes the cookies through the website data manager, since
+// webkit_cookie_manager_delete_all_cookies was removed in WebKitGTK 6.0.
+func (m *cookieManager) Clear(ctx context.Context) error {
+	var dataManager webkitgtk.WebKitWebsiteDataManager
+	err := m.call(ctx, func(manager webkitgtk.WebKitCookieManager, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback) {
+		dataManager = m.w.webkit.WebKitNetworkSessionGetWebsiteDataManager(m.w.session)
+		m.w.webkit.WebKitWebsiteDataManagerClear(dataManager, webkitgtk.WEBKIT_WEBSITE_DATA_COOKIES, 0, cancellable, callback, webkitgtk.NULLPTR)
+	}, func(manager webkitgtk.WebKitCookieManager, res webkitgtk.GAsyncResult) error {
+		return m.w.webkit.WebKitWebsiteDataManagerClearFinish(dataManager, res)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear cookies: %w", err)
+	}
+	return nil
+}
+
+func (m *cookieManager) SetPersistentStorage(filename string, storage CookieStorage) {
+	format := webkitgtk.WEBKIT_COOKIE_PERSISTENT_STORAGE_SQLITE
+	if storage == CookieStorageText {
+		format = webkitgtk.WEBKIT_COOKIE_PERSISTENT_STORAGE_TEXT
+	}
+
+	m.w.Dispatch(func() {
+		manager := m.w.webkit.WebKitNetworkSessionGetCookieManager(m.w.session)
+		m.w.webkit.WebKitCookieManagerSetPersistentStorage(manager, filename, format)
+	})
+}
+
+func (m *cookieManager) SetAcceptPolicy(policy CookieAcceptPolicy) {
+	var p webkitgtk.WebKitCookieAcceptPolicy
+	switch policy {
+	case CookieAcceptNever:
+		p = webkitgtk.WEBKIT_COOKIE_POLICY_ACCEPT_NEVER
+	case CookieAcceptNoThirdParty:
+		p = webkitgtk.WEBKIT_COOKIE_POLICY_ACCEPT_NO_THIRD_PARTY
+	default:
+		p = webkitgtk.WEBKIT_COOKIE_POLICY_ACCEPT_ALWAYS
+	}
+
+	m.w.Dispatch(func() {
+		manager := m.w.webkit.WebKitNetworkSessionGetCookieManager(m.w.session)
+		m.w.webkit.WebKitCookieManagerSetAcceptPolicy(manager, p)
+	})
+}
+
+// call runs start on the main thread and waits for the operation to call
+// back, or for ctx to be done. finish is called on the main thread with the
+// result.
+func (m *cookieManager) call(ctx context.Context, start func(manager webkitgtk.WebKitCookieManager, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback), finish func(manager webkitgtk.WebKitCookieManager, res webkitgtk.GAsyncResult) error) error {
+	done := make(chan error, 1)
+
+	if m.w.isDestroyed() {
+		return ErrDestroyed
+	}
+
+	// See EvalResult.
+	cancellable := m.w.webkit.GCancellableNew()
+	defer m.w.webkit.GObjectUnref(webkitgtk.GObject(cancellable))
+	m.w.webkit.GObjectRef(webkitgtk.GObject(cancellable))
+	m.w.app.Dispatch(func() {
+		// The session is released along with the web view.
+		if m.w.isDestroyed() {
+			m.w.webkit.GObjectUnref(webkitgtk.GObject(cancellable))
+			done <- ErrDestroyed
+			return
+		}
+		manager := m.w.webkit.WebKitNetworkSessionGetCookieManager(m.w.session)
+		start(manager, cancellable, func(sourceObject webkitgtk.GObject, res webkitgtk.GAsyncResult, userData uintptr) {
+			defer m.w.webkit.GObjectUnref(webkitgtk.GObject(cancellable))
+			done <- finish(manager, res)
+		})
+	})
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		m.w.webkit.GCancellableCancel(cancellable)
+		return ctx.Err()
+	}
+}
+
+// toSoupCookie applies the defaults of cookies received from uri.
+func toSoupCookie(uri string, cookie *http.Cookie) (webkitgtk.SoupCookieData, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return webkitgtk.SoupCookieData{}, fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	data := webkitgtk.SoupCookieData{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   u.Hostname(),
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HTTPOnly: cookie.HttpOnly,
+	}
+	// libsoup marks cookies for a domain and its subdomains with a leading
+	// dot, like the Domain attribute of Set-Cookie.
+	if cookie.Domain != "" {
+		data.Domain = "." + strings.TrimPrefix(cookie.Domain, ".")
+	}
+	if data.Path == "" {
+		data.Path = "/"
+	}
+
+	switch {
+	case cookie.MaxAge < 0:
+		data.Expires = 1
+	case cookie.MaxAge > 0:
+		data.Expires = time.Now().Unix() + int64(cookie.MaxAge)
+	case !cookie.Expires.IsZero():
+		data.Expires = cookie.Expires.Unix()
+	}
+
+	// Cookies without a SameSite attribute are treated as Lax.
+	switch cookie.SameSite {
+	case http.SameSiteNoneMode:
+		data.SameSite = webkitgtk.SOUP_SAME_SITE_POLICY_NONE
+	case http.SameSiteStrictMode:
+		data.SameSite = webkitgtk.SOUP_SAME_SITE_POLICY_STRICT
+	default:
+		data.SameSite = webkitgtk.SOUP_SAME_SITE_POLICY_LAX
+	}
+	return data, nil
+}
+
+func fromSoupCookie(data webkitgtk.SoupCookieData) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     data.Name,
+		Value:    data.Value,
+		Domain:   strings.TrimPrefix(data.Domain, "."),
+		Path:     data.Path,
+		Secure:   data.Secure,
+		HttpOnly: data.HTTPOnly,
+	}
+	if data.Expires != 0 {
+		cookie.Expires = time.Unix(data.Expires, 0)
+	}
+
+	switch data.SameSite {
+	case webkitgtk.SOUP_SAME_SITE_POLICY_LAX:
+		cookie.SameSite = http.SameSiteLaxMode
+	case webkitgtk.SOUP_SAME_SITE_POLICY_STRICT:
+		cookie.SameSite = http.SameSiteStrictMode
+	default:
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	return cookie
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package webview
+
+import (
+	"context"
+	"net/http"
+)
+
+// unsupportedCookieManager fails every operation with ErrNotSupported.
+type unsupportedCookieManager struct{}
+
+func (unsupportedCookieManager) Cookies(ctx context.Context, url string) ([]*http.Cookie, error) {
+	return nil, ErrNotSupported
+}
+
+func (unsupportedCookieManager) SetCookie(ctx context.Context, url string, cookie *http.Cookie) error {
+	return ErrNotSupported
+}
+
+func (unsupportedCookieManager) DeleteCookie(ctx context.Context, url string, cookie *http.Cookie) error {
+	return ErrNotSupported
+}
+
+func (unsupportedCookieManager) Clear(ctx context.Context) error {
+	return ErrNotSupported
+}
+
+func (unsupportedCookieManager) SetPersistentStorage(filename string, storage CookieStorage) {
+}
+
+func (unsupportedCookieManager) SetAcceptPolicy(policy CookieAcceptPolicy) {
+}
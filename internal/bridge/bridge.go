@@ -0,0 +1,661 @@
+// Package bridge implements the RPC bridge between Go and the JavaScript of a
+// webview. It is shared by all platform backends, which only need to deliver
+// the messages posted with window.external.invoke to OnMessage.
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Error codes defined by JSON-RPC 2.0. Codes from -32000 to -32099 are
+// reserved for implementation-defined server errors, the others are free for
+// application errors.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// RPCError is the error object of a JSON-RPC 2.0 response. JavaScript
+// promises of bound functions are rejected with it.
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// Method names of the notifications sent by the bridge's JavaScript, which
+// can not collide with bindings since they are not valid identifiers.
+const (
+	cancelMethod = "$/cancelRequest"
+	emitMethod   = "$/emit"
+)
+
+// Host is the webview a Bridge runs in.
+type Host interface {
+	// AddScript injects js into the pages loaded from now on, like
+	// WebView.Init, until remove is called.
+	AddScript(js string) (remove func())
+	Eval(js string)
+	Dispatch(f func())
+}
+
+// ResultHost may be implemented by a Host to also receive the results of
+// calls, in addition to the JavaScript that settles their promises.
+type ResultHost interface {
+	Host
+	Result(id int, result json.RawMessage, err error)
+}
+
+// Bridge holds the bindings and event listeners of a webview.
+type Bridge struct {
+	host     Host
+	bindings map[string]interface{}
+	mutex    sync.RWMutex
+
+	// scripts holds the functions removing the scripts that define bindings,
+	// by the name of their global: the binding, or the namespace of the
+	// methods bound with BindObject.
+	scripts map[string]func()
+
+	listeners    map[string]map[uint64]func(json.RawMessage)
+	nextListener uint64
+
+	// pending holds the cancel functions of running asynchronous calls by
+	// call ID. generation is bumped whenever the page navigates away.
+	pending    map[int]context.CancelFunc
+	generation uint64
+
+	// closed is set by Close, after which events are no longer emitted.
+	closed bool
+}
+
+// New creates a Bridge driving host.
+func New(host Host) *Bridge {
+	return &Bridge{
+		host:      host,
+		bindings:  make(map[string]interface{}),
+		scripts:   make(map[string]func()),
+		listeners: make(map[string]map[uint64]func(json.RawMessage)),
+		pending:   make(map[int]context.CancelFunc),
+	}
+}
+
+// Bindings returns a copy of the bound functions by name.
+func (b *Bridge) Bindings() map[string]interface{} {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	bindings := make(map[string]interface{}, len(b.bindings))
+	for name, f := range b.bindings {
+		bindings[name] = f
+	}
+	return bindings
+}
+
+// Bind exposes f to JavaScript as window[name], see WebView.Bind.
+func (b *Bridge) Bind(name string, f interface{}) error {
+	if err := checkBindable(reflect.ValueOf(f)); err != nil {
+		return err
+	}
+
+	b.unbind(name)
+	b.mutex.Lock()
+	b.bindings[name] = f
+	b.mutex.Unlock()
+
+	return b.install(name, []jsFunction{{Name: name, Method: name}})
+}
+
+// BindObject exposes the exported methods of obj to JavaScript as
+// window[namespace][method], see WebView.BindObject.
+func (b *Bridge) BindObject(namespace string, obj interface{}) error {
+	if namespace == "" {
+		return fmt.Errorf("namespace must not be empty")
+	}
+
+	v := reflect.ValueOf(obj)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return fmt.Errorf("object bound to %s must not be nil", namespace)
+	}
+	if v.NumMethod() == 0 {
+		return fmt.Errorf("%s has no exported methods", v.Type())
+	}
+
+	methods := make(map[string]interface{}, v.NumMethod())
+	functions := make([]jsFunction, 0, v.NumMethod())
+	for i := 0; i < v.NumMethod(); i++ {
+		goName := v.Type().Method(i).Name
+		if err := checkBindable(v.Method(i)); err != nil {
+			return fmt.Errorf("method %s: %w", goName, err)
+		}
+
+		name := MethodName(goName)
+		method := namespace + "." + name
+		methods[method] = v.Method(i).Interface()
+		functions = append(functions, jsFunction{Namespace: namespace, Name: name, Method: method})
+	}
+
+	b.unbind(namespace)
+	b.mutex.Lock()
+	for method, f := range methods {
+		b.bindings[method] = f
+	}
+	b.mutex.Unlock()
+
+	return b.install(namespace, functions)
+}
+
+// Unbind removes the function or namespace object bound as window[name], see
+// WebView.Unbind.
+func (b *Bridge) Unbind(name string) error {
+	if !b.unbind(name) {
+		return fmt.Errorf("%s is not bound", name)
+	}
+	return nil
+}
+
+// unbind removes the bindings of window[name] and their script, and reports
+// whether there were any.
+func (b *Bridge) unbind(name string) bool {
+	b.mutex.Lock()
+	remove, ok := b.scripts[name]
+	delete(b.scripts, name)
+	delete(b.bindings, name)
+	for method := range b.bindings {
+		if strings.HasPrefix(method, name+".") {
+			delete(b.bindings, method)
+		}
+	}
+	b.mutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	remove()
+	serName, _ := json.Marshal(name)
+	b.host.Eval(fmt.Sprintf(`delete window[%s];`, serName))
+	return true
+}
+
+// MethodName returns the JavaScript name of an exported Go method bound with
+// BindObject. The leading upper case letters are lowered, keeping the last
+// one of an initialism that starts a word: GetUser becomes getUser, HTTPGet
+// httpGet, ID id and URLs urls.
+func MethodName(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	plural := n < len(runes) && runes[n] == 's' && (n+1 == len(runes) || !unicode.IsLower(runes[n+1]))
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) && !plural {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func checkBindable(v reflect.Value) error {
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("only functions can be bound")
+	}
+
+	if n := v.Type().NumOut(); n > 2 {
+		return fmt.Errorf("function may only return a value or a value+error")
+	}
+	return nil
+}
+
+// jsFunction is a JavaScript function calling the binding Method, defined as
+// window[Name], or window[Namespace][Name] if Namespace isn't empty.
+type jsFunction struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Method    string `json:"method"`
+}
+
+// install defines functions in the current page and in the pages loaded
+// later, until the global name is unbound.
+func (b *Bridge) install(name string, functions []jsFunction) error {
+	serFunctions, err := json.Marshal(functions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal binding names: %w", err)
+	}
+
+	js := fmt.Sprintf(`(function() { var functions = %s;
+		var RPC = window._rpc = (window._rpc || {nextSeq: 1});
+		RPC.receive = RPC.receive || function(res) {
+			var call = RPC[res.id];
+			delete RPC[res.id];
+			if (!call) {
+				return;
+			}
+			if (res.error) {
+				call.reject(res.error);
+			} else {
+				call.resolve(res.result);
+			}
+		};
+		RPC.call = RPC.call || function(method, params) {
+			var seq = RPC.nextSeq++;
+			var promise = new Promise(function(resolve, reject) {
+				RPC[seq] = {
+					resolve: resolve,
+					reject: reject
+				};
+			});
+			promise.cancel = function() {
+				window.external.invoke(JSON.stringify({
+					jsonrpc: '2.0',
+					method: '%s',
+					params: {id: seq},
+				}));
+			};
+			window.external.invoke(JSON.stringify({
+				jsonrpc: '2.0',
+				id: seq,
+				method: method,
+				params: params,
+			}));
+			return promise;
+		};
+		functions.forEach(function(fn) {
+			var target = window;
+			if (fn.namespace) {
+				target = window[fn.namespace] = window[fn.namespace] || {};
+			}
+			target[fn.name] = function() {
+				return RPC.call(fn.method, Array.prototype.slice.call(arguments));
+			};
+		});
+	})();`, serFunctions, cancelMethod)
+	remove := b.host.AddScript(js)
+	b.mutex.Lock()
+	b.scripts[name] = remove
+	b.mutex.Unlock()
+	b.host.Eval(js)
+
+	return nil
+}
+
+// EventScript installs window.webview, the JavaScript side of Emit and On.
+const EventScript = `(function() {
+	var listeners = {};
+	window.webview = {
+		on: function(name, fn) {
+			(listeners[name] = listeners[name] || []).push(fn);
+			return function() { window.webview.off(name, fn); };
+		},
+		off: function(name, fn) {
+			var l = listeners[name] || [];
+			var i = l.indexOf(fn);
+			if (i >= 0) {
+				l.splice(i, 1);
+			}
+		},
+		emit: function(name, payload) {
+			window.external.invoke(JSON.stringify({
+				jsonrpc: '2.0',
+				method: '$/emit',
+				params: {event: name, payload: payload},
+			}));
+		},
+		_dispatch: function(name, payload) {
+			(listeners[name] || []).slice().forEach(function(fn) {
+				fn(payload);
+			});
+		}
+	};
+})();`
+
+// Emit dispatches an event to JavaScript, see WebView.Emit.
+func (b *Bridge) Emit(event string, payload interface{}) error {
+	serEvent, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event name: %w", err)
+	}
+	serPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	b.mutex.RLock()
+	closed := b.closed
+	b.mutex.RUnlock()
+	if closed {
+		return nil
+	}
+
+	js := fmt.Sprintf(`window.webview && window.webview._dispatch(%s, %s);`, serEvent, serPayload)
+	b.host.Dispatch(func() {
+		b.host.Eval(js)
+	})
+
+	return nil
+}
+
+// On subscribes f to events from JavaScript, see WebView.On.
+func (b *Bridge) On(event string, f func(payload json.RawMessage)) (off func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.nextListener++
+	id := b.nextListener
+	if b.listeners[event] == nil {
+		b.listeners[event] = make(map[uint64]func(json.RawMessage))
+	}
+	b.listeners[event][id] = f
+
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.listeners[event], id)
+	}
+}
+
+func (b *Bridge) onEvent(event string, payload json.RawMessage) {
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+
+	b.mutex.RLock()
+	listeners := make([]func(json.RawMessage), 0, len(b.listeners[event]))
+	for _, f := range b.listeners[event] {
+		listeners = append(listeners, f)
+	}
+	b.mutex.RUnlock()
+
+	for _, f := range listeners {
+		f(payload)
+	}
+}
+
+// rpcMessage is a JSON-RPC 2.0 request, or a notification if it has no ID.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// cancelParams are the params of a cancelMethod notification, sent when
+// JavaScript calls cancel() on the promise of the call with the given ID.
+type cancelParams struct {
+	ID int `json:"id"`
+}
+
+// emitParams are the params of an emitMethod notification, sent with
+// window.webview.emit.
+type emitParams struct {
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// takesContext reports whether a bound function expects a context.Context as
+// its first argument. Such functions are called on their own goroutine.
+func takesContext(t reflect.Type) bool {
+	return t.NumIn() > 0 && t.In(0) == contextType
+}
+
+// OnMessage handles a message posted with window.external.invoke. It must be
+// called on the main thread.
+func (b *Bridge) OnMessage(msg string) {
+	b.OnMessageContext(context.Background(), msg)
+}
+
+// OnMessageContext is like OnMessage, with the contexts passed to bindings
+// derived from ctx.
+func (b *Bridge) OnMessageContext(ctx context.Context, msg string) {
+	var req rpcMessage
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		log.Printf("invalid RPC message: %v", err)
+		return
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID != nil {
+			b.respond(*req.ID, nil, &RPCError{Code: InvalidRequest, Message: "Invalid Request"})
+		} else {
+			log.Printf("invalid RPC message: not a JSON-RPC 2.0 request")
+		}
+		return
+	}
+
+	if req.ID == nil {
+		b.onNotification(ctx, req)
+		return
+	}
+	id := *req.ID
+
+	b.mutex.RLock()
+	f, ok := b.bindings[req.Method]
+	b.mutex.RUnlock()
+	if !ok || !takesContext(reflect.TypeOf(f)) {
+		res, err := b.callBinding(ctx, req)
+		b.respond(id, res, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	b.mutex.Lock()
+	b.pending[id] = cancel
+	generation := b.generation
+	b.mutex.Unlock()
+
+	go func() {
+		res, err := b.callBinding(ctx, req)
+		b.host.Dispatch(func() {
+			b.mutex.Lock()
+			current := b.generation == generation
+			if current {
+				delete(b.pending, id)
+			}
+			b.mutex.Unlock()
+			cancel()
+
+			// The page that made the call is gone, and its IDs may have been
+			// reused by the new one.
+			if current {
+				b.respond(id, res, err)
+			}
+		})
+	}()
+}
+
+// onNotification handles the messages that expect no response. Bound
+// functions called as notifications run without reporting their result.
+func (b *Bridge) onNotification(ctx context.Context, req rpcMessage) {
+	switch req.Method {
+	case cancelMethod:
+		var params cancelParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			log.Printf("invalid RPC message: %v", err)
+			return
+		}
+
+		b.mutex.RLock()
+		cancel, ok := b.pending[params.ID]
+		b.mutex.RUnlock()
+		if ok {
+			cancel()
+		}
+	case emitMethod:
+		var params emitParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			log.Printf("invalid RPC message: %v", err)
+			return
+		}
+
+		b.onEvent(params.Event, params.Payload)
+	default:
+		if _, err := b.callBinding(ctx, req); err != nil {
+			log.Printf("RPC notification %s failed: %v", req.Method, err)
+		}
+	}
+}
+
+// CancelPendingCalls cancels the contexts of all running asynchronous calls.
+// Hosts must call it when the page that made them navigates away.
+func (b *Bridge) CancelPendingCalls() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, cancel := range b.pending {
+		cancel()
+	}
+	b.pending = make(map[int]context.CancelFunc)
+	b.generation++
+}
+
+// Close cancels the pending calls and drops all bindings and listeners. The
+// scripts of the bindings are left to the host.
+func (b *Bridge) Close() {
+	b.CancelPendingCalls()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	b.bindings = make(map[string]interface{})
+	b.scripts = make(map[string]func())
+	b.listeners = make(map[string]map[uint64]func(json.RawMessage))
+}
+
+// respond settles the JavaScript promise of the call with the given ID.
+// Errors other than *RPCError are reported as internal errors.
+func (b *Bridge) respond(id int, res interface{}, err error) {
+	var serRes []byte
+	if err == nil {
+		serRes, err = json.Marshal(res)
+		if err != nil {
+			err = fmt.Errorf("failed to marshal result: %w", err)
+		}
+	}
+	if rh, ok := b.host.(ResultHost); ok {
+		rh.Result(id, serRes, err)
+	}
+
+	resp := rpcResponse{JSONRPC: "2.0", ID: id, Result: serRes}
+	if err != nil {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: InternalError, Message: err.Error()}
+		}
+		resp.Result = nil
+		resp.Error = rpcErr
+	}
+
+	serResp, err := json.Marshal(resp)
+	if err != nil {
+		// The data of an RPCError could not be marshalled.
+		resp.Error = &RPCError{Code: InternalError, Message: resp.Error.Message}
+		serResp, _ = json.Marshal(resp)
+	}
+	b.host.Eval(fmt.Sprintf(`window._rpc.receive(%s);`, serResp))
+}
+
+// callBinding calls the binding of req. A panicking binding fails the call
+// with an internal error rather than crashing the application.
+func (b *Bridge) callBinding(ctx context.Context, req rpcMessage) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, &RPCError{Code: InternalError, Message: fmt.Sprintf("%s panicked: %v", req.Method, p)}
+		}
+	}()
+
+	b.mutex.RLock()
+	f, ok := b.bindings[req.Method]
+	b.mutex.RUnlock()
+	if !ok {
+		return nil, &RPCError{Code: MethodNotFound, Message: "Method not found", Data: req.Method}
+	}
+
+	var params []json.RawMessage
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &RPCError{Code: InvalidParams, Message: "Invalid params", Data: "params must be an array"}
+		}
+	}
+
+	v := reflect.ValueOf(f)
+	args := []reflect.Value{}
+	offset := 0
+	if takesContext(v.Type()) {
+		args = append(args, reflect.ValueOf(ctx))
+		offset = 1
+	}
+
+	isVariadic := v.Type().IsVariadic()
+	numIn := v.Type().NumIn() - offset
+	if (isVariadic && len(params) < numIn-1) || (!isVariadic && len(params) != numIn) {
+		return nil, &RPCError{Code: InvalidParams, Message: "Invalid params", Data: fmt.Sprintf("function arguments mismatch: expected %d, got %d", numIn, len(params))}
+	}
+
+	for i := range params {
+		var arg reflect.Value
+		if isVariadic && i >= numIn-1 {
+			arg = reflect.New(v.Type().In(offset + numIn - 1).Elem())
+		} else {
+			arg = reflect.New(v.Type().In(offset + i))
+		}
+		if err := json.Unmarshal(params[i], arg.Interface()); err != nil {
+			return nil, &RPCError{Code: InvalidParams, Message: "Invalid params", Data: fmt.Sprintf("failed to unmarshal argument %d: %v", i, err)}
+		}
+		args = append(args, arg.Elem())
+	}
+
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	res := v.Call(args)
+	switch len(res) {
+	case 0:
+		// No results from the function, just return nil
+		return nil, nil
+	case 1:
+		// One result may be a value, or an error
+		if res[0].Type().Implements(errorType) {
+			if res[0].Interface() != nil {
+				return nil, res[0].Interface().(error)
+			}
+			return nil, nil
+		}
+		return res[0].Interface(), nil
+	case 2:
+		// Two results: first one is value, second one is error
+		if !res[1].Type().Implements(errorType) {
+			return nil, fmt.Errorf("second return value must be an error, got %s", res[1].Type().String())
+		}
+		if res[1].Interface() != nil {
+			return res[0].Interface(), res[1].Interface().(error)
+		}
+		return res[0].Interface(), nil
+	default:
+		return nil, fmt.Errorf("unexpected number of return values: %d", len(res))
+	}
+}
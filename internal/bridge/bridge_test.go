@@ -0,0 +1,21 @@
+package bridge
+
+import "testing"
+
+func TestMethodName(t *testing.T) {
+	tests := map[string]string{
+		"GetUser":   "getUser",
+		"HTTPGet":   "httpGet",
+		"ID":        "id",
+		"URLs":      "urls",
+		"URLsByID":  "urlsByID",
+		"X":         "x",
+		"Get":       "get",
+		"HTMLToURL": "htmlToURL",
+	}
+	for name, want := range tests {
+		if got := MethodName(name); got != want {
+			t.Errorf("MethodName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
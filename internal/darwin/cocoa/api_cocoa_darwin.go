@@ -53,6 +53,8 @@ var (
 	sel_setFrameDisplayAnimate                          objc.SEL
 	sel_setContentMinSize                               objc.SEL
 	sel_setContentMaxSize                               objc.SEL
+	sel_setContentAspectRatio                           objc.SEL
+	sel_setContentResizeIncrements                      objc.SEL
 	sel_center                                          objc.SEL
 	sel_setStyleMask                                    objc.SEL
 	sel_loadHTMLStringBaseURL                           objc.SEL
@@ -117,6 +119,8 @@ func init() {
 	sel_setFrameDisplayAnimate = objc.RegisterName("setFrame:display:animate:")
 	sel_setContentMinSize = objc.RegisterName("setContentMinSize:")
 	sel_setContentMaxSize = objc.RegisterName("setContentMaxSize:")
+	sel_setContentAspectRatio = objc.RegisterName("setContentAspectRatio:")
+	sel_setContentResizeIncrements = objc.RegisterName("setContentResizeIncrements:")
 	sel_center = objc.RegisterName("center")
 	sel_setStyleMask = objc.RegisterName("setStyleMask:")
 	sel_stringWithUTF8String = objc.RegisterName("stringWithUTF8String:")
@@ -179,6 +183,7 @@ type WKUserScriptInjectionTime NSInteger
 
 const (
 	WKUserScriptInjectionTimeAtDocumentStart WKUserScriptInjectionTime = 0
+	WKUserScriptInjectionTimeAtDocumentEnd   WKUserScriptInjectionTime = 1
 )
 
 func NSApplication_GetSharedApplication() NSApplication {
@@ -357,10 +362,30 @@ func (window NSWindow) SetContentMaxSize(size NSSize) {
 	inv.Invoke()
 }
 
+func (window NSWindow) SetContentAspectRatio(size NSSize) {
+	inv := NSInvocation_invocationWithMethodSignature(NSMethodSignature_signatureWithObjCTypes("v@:{CGSize=dd}"))
+	inv.SetTarget(window.ID)
+	inv.SetSelector(sel_setContentAspectRatio)
+	inv.SetArgumentAtIndex(unsafe.Pointer(&size), 2)
+	inv.Invoke()
+}
+
+func (window NSWindow) SetContentResizeIncrements(size NSSize) {
+	inv := NSInvocation_invocationWithMethodSignature(NSMethodSignature_signatureWithObjCTypes("v@:{CGSize=dd}"))
+	inv.SetTarget(window.ID)
+	inv.SetSelector(sel_setContentResizeIncrements)
+	inv.SetArgumentAtIndex(unsafe.Pointer(&size), 2)
+	inv.Invoke()
+}
+
 func (window NSWindow) Center() {
 	window.Send(sel_center)
 }
 
+func (window NSWindow) Close() {
+	window.Send(objc.RegisterName("close"))
+}
+
 func (window NSWindow) SetTitle(title string) {
 	wrappedTitle := NSString_alloc().InitWithUTF8String(title)
 	window.Send(objc.RegisterName("setTitle:"), wrappedTitle.ID)
@@ -489,6 +514,15 @@ func (c WKUserContentController) AddUserScript(script WKUserScript) {
 	c.Send(objc.RegisterName("addUserScript:"), script.ID)
 }
 
+func (c WKUserContentController) RemoveScriptMessageHandlerForName(name string) {
+	wrappedName := NSString_alloc().InitWithUTF8String(name)
+	c.Send(objc.RegisterName("removeScriptMessageHandlerForName:"), wrappedName.ID)
+}
+
+func (c WKUserContentController) RemoveAllUserScripts() {
+	c.Send(objc.RegisterName("removeAllUserScripts"))
+}
+
 type WKWebView struct {
 	objc.ID
 }
@@ -510,6 +544,10 @@ func (w WKWebView) InitWithFrame(frame NSRect, configuration WKWebViewConfigurat
 	return ret
 }
 
+func (w WKWebView) RemoveFromSuperview() {
+	w.Send(objc.RegisterName("removeFromSuperview"))
+}
+
 func (w WKWebView) SetUIDelegate(delegate objc.ID) {
 	w.Send(objc.RegisterName("setUIDelegate:"), delegate)
 }
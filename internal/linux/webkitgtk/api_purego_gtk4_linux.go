@@ -0,0 +1,256 @@
+//go:build linux
+
+package webkitgtk
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// gtk4Context implements Context for WebKitGTK 6.0, which is built against
+// GTK 4. Functions that are unchanged between the ABIs are inherited from
+// defaultContext, the others are mapped to their GTK 4 replacements.
+type gtk4Context struct {
+	*defaultContext
+
+	mainLoop uintptr
+
+	// GLib
+	gMainLoopNew  uintptr
+	gMainLoopQuit uintptr
+	gMainLoopRun  uintptr
+	gObjectNew    uintptr
+
+	// GTK
+	gtkWidgetSetVisible     uintptr
+	gtkWindowDestroy        uintptr
+	gtkWindowGetDefaultSize uintptr
+	gtkWindowMinimize       uintptr
+	gtkWindowSetChild       uintptr
+
+	// WebKit
+	webKitNetworkSessionGetCookieManager      uintptr
+	webKitNetworkSessionGetDefault            uintptr
+	webKitNetworkSessionGetWebsiteDataManager uintptr
+	webKitNetworkSessionNew                   uintptr
+	webKitNetworkSessionNewEphemeral          uintptr
+	webKitWebViewEvaluateJavascript           uintptr
+	webKitWebViewEvaluateJavascriptFinish     uintptr
+	webKitWebViewGetType                      uintptr
+}
+
+// NewGtk4Context creates a Context for a WebKitGTK 6.0 library returned by
+// OpenLibrary.
+func NewGtk4Context(lib *Library) (Context, error) {
+	if lib.ABI != ABI60 {
+		return nil, fmt.Errorf("failed to initialize GTK 4 context: %s (%s) is not a GTK 4 library", lib.Name, lib.ABI)
+	}
+
+	return &gtk4Context{
+		defaultContext: &defaultContext{lib: lib},
+	}, nil
+}
+
+// GtkContainerAdd sets widget as the child of container, which must be a
+// window since GTK 4 has no generic containers.
+func (c *gtk4Context) GtkContainerAdd(container GtkContainer, widget GtkWidget) {
+	purego.SyscallN(c.gtkWindowSetChild, uintptr(container), uintptr(widget))
+}
+
+// GtkContainerRemove unsets widget as the child of container, which must be
+// a window.
+func (c *gtk4Context) GtkContainerRemove(container GtkContainer, widget GtkWidget) {
+	purego.SyscallN(c.gtkWindowSetChild, uintptr(container), NULLPTR)
+}
+
+func (c *gtk4Context) GtkMain() {
+	purego.SyscallN(c.gMainLoopRun, c.mainLoop)
+}
+
+func (c *gtk4Context) GtkMainQuit() {
+	purego.SyscallN(c.gMainLoopQuit, c.mainLoop)
+}
+
+// GtkWidgetShowAll shows widget. Children are visible by default in GTK 4.
+func (c *gtk4Context) GtkWidgetShowAll(widget GtkWidget) {
+	purego.SyscallN(c.gtkWidgetSetVisible, uintptr(widget), uintptr(boolToInt(true)))
+}
+
+func (c *gtk4Context) GtkWindowDestroy(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowDestroy, uintptr(window))
+}
+
+// GtkWindowResize sets the default size of window, which GTK 4 also applies
+// to windows that are already shown.
+func (c *gtk4Context) GtkWindowResize(window GtkWindow, width, height int) {
+	c.GtkWindowSetDefaultSize(window, width, height)
+}
+
+// GtkWindowSetPosition does nothing, see GtkWindowMove.
+func (c *gtk4Context) GtkWindowSetPosition(window GtkWindow, position GtkWindowPosition) {
+}
+
+// GtkWindowSetIcon does nothing, GTK 4 only supports named icons from the
+// icon theme.
+func (c *gtk4Context) GtkWindowSetIcon(window GtkWindow, icon GdkPixbuf) {
+}
+
+// GtkWindowGetSize returns the default size of window, which GTK 4 keeps up
+// to date as the window is resized.
+func (c *gtk4Context) GtkWindowGetSize(window GtkWindow) (width, height int) {
+	var w, h int32
+	purego.SyscallN(c.gtkWindowGetDefaultSize, uintptr(window), uintptr(unsafe.Pointer(&w)), uintptr(unsafe.Pointer(&h)))
+	return int(w), int(h)
+}
+
+// GtkWindowMove does nothing, GTK 4 leaves window placement to the window
+// manager.
+func (c *gtk4Context) GtkWindowMove(window GtkWindow, x, y int) {
+}
+
+// GtkWindowGetPosition always returns 0, 0, see GtkWindowMove.
+func (c *gtk4Context) GtkWindowGetPosition(window GtkWindow) (x, y int) {
+	return 0, 0
+}
+
+func (c *gtk4Context) GtkWindowIconify(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowMinimize, uintptr(window))
+}
+
+// GtkWindowSetKeepAbove does nothing, GTK 4 has no replacement.
+func (c *gtk4Context) GtkWindowSetKeepAbove(window GtkWindow, setting bool) {
+}
+
+// GtkWindowSetGeometryHints only supports GDK_HINT_MIN_SIZE, which is
+// applied as the size request of the window. GTK 4 leaves all other
+// constraints to the window manager.
+func (c *gtk4Context) GtkWindowSetGeometryHints(window GtkWindow, geometryWidget GtkWidget, geometry GdkGeometry, geomMask GdkWindowHints) {
+	if geomMask&GDK_HINT_MIN_SIZE != 0 {
+		c.GtkWidgetSetSizeRequest(GtkWidget(window), int(geometry.MinWidth), int(geometry.MinHeight))
+	} else {
+		c.GtkWidgetSetSizeRequest(GtkWidget(window), -1, -1)
+	}
+}
+
+func (c *gtk4Context) WebKitNetworkSessionGetDefault() WebKitNetworkSession {
+	ret, _, _ := purego.SyscallN(c.webKitNetworkSessionGetDefault)
+	return WebKitNetworkSession(ret)
+}
+
+func (c *gtk4Context) WebKitNetworkSessionNew(dataDirectory string, cacheDirectory string) WebKitNetworkSession {
+	cstrDataDirectory, free := cStr(dataDirectory)
+	defer free()
+	cstrCacheDirectory, free := cStr(cacheDirectory)
+	defer free()
+	ret, _, _ := purego.SyscallN(c.webKitNetworkSessionNew, uintptr(unsafe.Pointer(cstrDataDirectory)), uintptr(unsafe.Pointer(cstrCacheDirectory)))
+	return WebKitNetworkSession(ret)
+}
+
+func (c *gtk4Context) WebKitNetworkSessionNewEphemeral() WebKitNetworkSession {
+	ret, _, _ := purego.SyscallN(c.webKitNetworkSessionNewEphemeral)
+	return WebKitNetworkSession(ret)
+}
+
+func (c *gtk4Context) WebKitNetworkSessionGetCookieManager(session WebKitNetworkSession) WebKitCookieManager {
+	ret, _, _ := purego.SyscallN(c.webKitNetworkSessionGetCookieManager, uintptr(session))
+	return WebKitCookieManager(ret)
+}
+
+func (c *gtk4Context) WebKitNetworkSessionGetWebsiteDataManager(session WebKitNetworkSession) WebKitWebsiteDataManager {
+	ret, _, _ := purego.SyscallN(c.webKitNetworkSessionGetWebsiteDataManager, uintptr(session))
+	return WebKitWebsiteDataManager(ret)
+}
+
+// WebKitWebViewNewWithNetworkSession sets the construct-only network-session
+// property, since WebKitGTK 6.0 has no constructor taking it.
+func (c *gtk4Context) WebKitWebViewNewWithNetworkSession(session WebKitNetworkSession) GtkWidget {
+	cstrProperty, free := cStr("network-session")
+	defer free()
+	typ, _, _ := purego.SyscallN(c.webKitWebViewGetType)
+	ret, _, _ := purego.SyscallN(c.gObjectNew, typ, uintptr(unsafe.Pointer(cstrProperty)), uintptr(session), NULLPTR)
+	return GtkWidget(ret)
+}
+
+// WebKitWebViewRunJavascript is implemented with
+// webkit_web_view_evaluate_javascript.
+func (c *gtk4Context) WebKitWebViewRunJavascript(webview WebKitWebView, script string, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr) {
+	cstrScript, free := cStr(script)
+	defer free()
+
+	callbackCb, handle := registerAsyncReady(callback, userData)
+
+	length := -1
+	purego.SyscallN(c.webKitWebViewEvaluateJavascript, uintptr(webview), uintptr(unsafe.Pointer(cstrScript)), uintptr(length), NULLPTR, NULLPTR, uintptr(cancellable), callbackCb, handle)
+}
+
+// WebKitWebViewRunJavascriptFinish returns the JSCValue itself, since
+// WebKitJavascriptResult no longer exists in WebKitGTK 6.0.
+func (c *gtk4Context) WebKitWebViewRunJavascriptFinish(webview WebKitWebView, result GAsyncResult) (WebKitJavascriptResult, error) {
+	var gerr uintptr
+	ret, _, _ := purego.SyscallN(c.webKitWebViewEvaluateJavascriptFinish, uintptr(webview), uintptr(result), uintptr(unsafe.Pointer(&gerr)))
+	if ret == NULLPTR {
+		return WebKitJavascriptResult(NULLPTR), c.takeError(gerr)
+	}
+	return WebKitJavascriptResult(ret), nil
+}
+
+// WebKitJavascriptResultGetJsValue returns jsResult unchanged. In WebKitGTK
+// 6.0 both script messages and evaluation results are JSCValues.
+func (c *gtk4Context) WebKitJavascriptResultGetJsValue(jsResult WebKitJavascriptResult) JSCValue {
+	return JSCValue(jsResult)
+}
+
+func (c *gtk4Context) WebKitJavascriptResultUnref(jsResult WebKitJavascriptResult) {
+	c.GObjectUnref(GObject(jsResult))
+}
+
+// WebKitUserContentManagerRegisterScriptMessageHandler registers the handler
+// in the default script world, which WebKitGTK 6.0 takes as an additional
+// argument.
+func (c *gtk4Context) WebKitUserContentManagerRegisterScriptMessageHandler(manager WebKitUserContentManager, name string) {
+	cstrName, free := cStr(name)
+	defer free()
+	purego.SyscallN(c.webKitUserContentManagerRegisterScriptMessageHandler, uintptr(manager), uintptr(unsafe.Pointer(cstrName)), NULLPTR)
+}
+
+func (c *gtk4Context) WebKitUserContentManagerUnregisterScriptMessageHandler(manager WebKitUserContentManager, name string) {
+	cstrName, free := cStr(name)
+	defer free()
+	purego.SyscallN(c.webKitUserContentManagerUnregisterScriptMessageHandler, uintptr(manager), uintptr(unsafe.Pointer(cstrName)), NULLPTR)
+}
+
+func (c *gtk4Context) LoadFunctions() error {
+	g := &procAddressGetter{ctx: c.defaultContext}
+	c.loadFunctions(g)
+
+	// GLib
+	c.gMainLoopNew = g.get("g_main_loop_new")
+	c.gMainLoopQuit = g.get("g_main_loop_quit")
+	c.gMainLoopRun = g.get("g_main_loop_run")
+	c.gObjectNew = g.get("g_object_new")
+
+	// GTK 4 and WebKitGTK 6.0 only
+	c.gtkWidgetSetVisible = g.get("gtk_widget_set_visible")
+	c.gtkWindowDestroy = g.get("gtk_window_destroy")
+	c.gtkWindowGetDefaultSize = g.get("gtk_window_get_default_size")
+	c.gtkWindowMinimize = g.get("gtk_window_minimize")
+	c.gtkWindowSetChild = g.get("gtk_window_set_child")
+	c.webKitNetworkSessionGetCookieManager = g.get("webkit_network_session_get_cookie_manager")
+	c.webKitNetworkSessionGetDefault = g.get("webkit_network_session_get_default")
+	c.webKitNetworkSessionGetWebsiteDataManager = g.get("webkit_network_session_get_website_data_manager")
+	c.webKitNetworkSessionNew = g.get("webkit_network_session_new")
+	c.webKitNetworkSessionNewEphemeral = g.get("webkit_network_session_new_ephemeral")
+	c.webKitWebViewEvaluateJavascript = g.get("webkit_web_view_evaluate_javascript")
+	c.webKitWebViewEvaluateJavascriptFinish = g.get("webkit_web_view_evaluate_javascript_finish")
+	c.webKitWebViewGetType = g.get("webkit_web_view_get_type")
+
+	if g.err != nil {
+		return fmt.Errorf("failed to load functions: %w", g.err)
+	}
+
+	c.mainLoop, _, _ = purego.SyscallN(c.gMainLoopNew, NULLPTR, uintptr(boolToInt(false)))
+
+	return nil
+}
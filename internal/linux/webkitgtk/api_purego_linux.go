@@ -5,88 +5,359 @@ package webkitgtk
 import (
 	"fmt"
 	"runtime"
+	"time"
 	"unsafe"
 
 	"github.com/ebitengine/purego"
 )
 
 type defaultContext struct {
+	lib *Library
+
 	// GTK
+	gCancellableCancel        uintptr
+	gCancellableNew           uintptr
+	gErrorFree                uintptr
+	gErrorNewLiteral          uintptr
+	gDateTimeToUnix           uintptr
 	gFree                     uintptr
 	gIdleAddFull              uintptr
+	gInputStreamReadAll       uintptr
+	gListFreeFull             uintptr
+	gObjectRef                uintptr
+	gObjectUnref              uintptr
+	gQuarkFromString          uintptr
+	gQuarkToString            uintptr
 	gSignalConnectData        uintptr
+	gSignalHandlerDisconnect  uintptr
+	gUnixInputStreamNew       uintptr
+	gdkPixbufLoaderNew        uintptr
+	gdkPixbufLoaderWrite      uintptr
+	gdkPixbufLoaderClose      uintptr
+	gdkPixbufLoaderGetPixbuf  uintptr
 	gtkContainerAdd           uintptr
+	gtkContainerRemove        uintptr
 	gtkInitCheck              uintptr
 	gtkMain                   uintptr
 	gtkMainQuit               uintptr
 	gtkWidgetGrabFocus        uintptr
 	gtkWidgetSetSizeRequest   uintptr
 	gtkWidgetShowAll          uintptr
+	gtkWidgetDestroy          uintptr
 	gtkWindowNew              uintptr
 	gtkWindowResize           uintptr
+	gtkWindowSetDefaultSize   uintptr
+	gtkWindowSetPosition      uintptr
+	gtkWindowSetIcon          uintptr
+	gtkWindowGetSize          uintptr
+	gtkWindowMove             uintptr
+	gtkWindowGetPosition      uintptr
+	gtkWindowMaximize         uintptr
+	gtkWindowUnmaximize       uintptr
+	gtkWindowIconify          uintptr
+	gtkWindowFullscreen       uintptr
+	gtkWindowUnfullscreen     uintptr
+	gtkWindowSetKeepAbove     uintptr
+	gtkWindowSetDecorated     uintptr
+	gtkWindowIsActive         uintptr
+	gtkWindowIsMaximized      uintptr
+	gtkWindowIsFullscreen     uintptr
 	gtkWindowSetGeometryHints uintptr
 	gtkWindowSetResizable     uintptr
 	gtkWindowSetTitle         uintptr
 
 	// WebKit
-	jsCValueToString                                     uintptr
-	webKitGetMajorVersion                                uintptr
-	webKitGetMinorVersion                                uintptr
-	webKitGetMicroVersion                                uintptr
-	webKitWebViewNew                                     uintptr
-	webKitWebViewGetSettings                             uintptr
-	webKitWebViewGetUserContentManager                   uintptr
-	webKitWebViewLoadHTML                                uintptr
-	webKitWebViewLoadURI                                 uintptr
-	webKitWebViewRunJavascript                           uintptr
-	webKitJavascriptResultGetJsValue                     uintptr
-	webKitUserContentManagerAddScript                    uintptr
-	webKitUserContentManagerRegisterScriptMessageHandler uintptr
-	webKitUserScriptNew                                  uintptr
-	webKitSettingsSetEnableDeveloperExtras               uintptr
-	webKitSettingsSetEnableWriteConsoleMessagesToStdout  uintptr
-	webKitSettingsSetJavascriptCanAccessClipboard        uintptr
+	jsCValueToJSON                                         uintptr
+	jsCValueToString                                       uintptr
+	webKitGetMajorVersion                                  uintptr
+	webKitGetMinorVersion                                  uintptr
+	webKitGetMicroVersion                                  uintptr
+	webKitWebViewNew                                       uintptr
+	webKitWebViewNewWithContext                            uintptr
+	webKitWebContextGetDefault                             uintptr
+	webKitWebContextNewEphemeral                           uintptr
+	webKitWebContextNewWithWebsiteDataManager              uintptr
+	webKitWebContextGetCookieManager                       uintptr
+	webKitWebsiteDataManagerNew                            uintptr
+	webKitCookieManagerSetPersistentStorage                uintptr
+	webKitCookieManagerSetAcceptPolicy                     uintptr
+	webKitCookieManagerGetCookies                          uintptr
+	webKitCookieManagerGetCookiesFinish                    uintptr
+	webKitCookieManagerAddCookie                           uintptr
+	webKitCookieManagerAddCookieFinish                     uintptr
+	webKitCookieManagerDeleteCookie                        uintptr
+	webKitCookieManagerDeleteCookieFinish                  uintptr
+	webKitWebContextGetWebsiteDataManager                  uintptr
+	webKitWebsiteDataManagerClear                          uintptr
+	webKitWebsiteDataManagerClearFinish                    uintptr
+	webKitWebViewGetSettings                               uintptr
+	webKitWebViewGetUserContentManager                     uintptr
+	webKitWebViewLoadHTML                                  uintptr
+	webKitWebViewLoadAlternateHTML                         uintptr
+	webKitWebViewGetURI                                    uintptr
+	webKitWebViewGetMainResource                           uintptr
+	webKitWebResourceGetResponse                           uintptr
+	webKitURIResponseGetStatusCode                         uintptr
+	webKitURIResponseGetMIMEType                           uintptr
+	webKitURIRequestGetURI                                 uintptr
+	webKitPolicyDecisionUse                                uintptr
+	webKitPolicyDecisionIgnore                             uintptr
+	webKitNavigationPolicyDecisionGetNavigationAction      uintptr
+	webKitNavigationActionGetRequest                       uintptr
+	webKitNavigationActionIsUserGesture                    uintptr
+	webKitResponsePolicyDecisionGetRequest                 uintptr
+	webKitResponsePolicyDecisionGetResponse                uintptr
+	webKitResponsePolicyDecisionIsMainFrameMainResource    uintptr
+	webKitWebViewLoadURI                                   uintptr
+	webKitWebViewRunJavascript                             uintptr
+	webKitWebViewRunJavascriptFinish                       uintptr
+	webKitJavascriptResultGetJsValue                       uintptr
+	webKitJavascriptResultUnref                            uintptr
+	webKitUserContentManagerAddScript                      uintptr
+	webKitUserContentManagerRemoveAllScripts               uintptr
+	webKitUserContentManagerRegisterScriptMessageHandler   uintptr
+	webKitUserContentManagerUnregisterScriptMessageHandler uintptr
+	webKitUserScriptNew                                    uintptr
+	webKitUserScriptUnref                                  uintptr
+	webKitSettingsSetEnableDeveloperExtras                 uintptr
+	webKitSettingsSetEnableWriteConsoleMessagesToStdout    uintptr
+	webKitSettingsSetJavascriptCanAccessClipboard          uintptr
+	webKitWebViewGetContext                                uintptr
+	webKitWebContextRegisterURIScheme                      uintptr
+	webKitWebContextGetSecurityManager                     uintptr
+	webKitSecurityManagerRegisterURISchemeAsSecure         uintptr
+	webKitSecurityManagerRegisterURISchemeAsCorsEnabled    uintptr
+	webKitURISchemeRequestGetURI                           uintptr
+	webKitURISchemeRequestGetWebView                       uintptr
+	webKitURISchemeRequestGetHTTPMethod                    uintptr
+	webKitURISchemeRequestGetHTTPHeaders                   uintptr
+	webKitURISchemeRequestGetHTTPBody                      uintptr
+	webKitURISchemeRequestFinish                           uintptr
+	webKitURISchemeRequestFinishWithResponse               uintptr
+	webKitURISchemeRequestFinishError                      uintptr
+	webKitURISchemeResponseNew                             uintptr
+	webKitURISchemeResponseSetStatus                       uintptr
+	webKitURISchemeResponseSetContentType                  uintptr
+	webKitURISchemeResponseSetHTTPHeaders                  uintptr
+
+	// Soup
+	soupMessageHeadersNew     uintptr
+	soupMessageHeadersAppend  uintptr
+	soupMessageHeadersForeach uintptr
+	soupCookieNew             uintptr
+	soupCookieFree            uintptr
+	soupCookieGetName         uintptr
+	soupCookieGetValue        uintptr
+	soupCookieGetDomain       uintptr
+	soupCookieGetPath         uintptr
+	soupCookieGetExpires      uintptr
+	soupCookieGetSecure       uintptr
+	soupCookieGetHTTPOnly     uintptr
+	soupCookieGetSameSite     uintptr
+	soupCookieSetSecure       uintptr
+	soupCookieSetHTTPOnly     uintptr
+	soupCookieSetSameSite     uintptr
+	soupDateToTimeT           uintptr
 }
 
+// NewDefaultContext loads the preferred WebKitGTK library, see OpenLibrary,
+// and creates the Context matching its ABI.
 func NewDefaultContext() (Context, error) {
+	lib, err := OpenLibrary("")
+	if err != nil {
+		return nil, err
+	}
+
+	if lib.ABI == ABI60 {
+		return NewGtk4Context(lib)
+	}
+	return NewGtk3Context(lib)
+}
+
+// NewGtk3Context creates a Context for a WebKit2GTK library returned by
+// OpenLibrary.
+func NewGtk3Context(lib *Library) (Context, error) {
 	ctx := &defaultContext{}
-	if err := ctx.init(); err != nil {
+	if err := ctx.init(lib); err != nil {
 		return nil, fmt.Errorf("failed to initialize default context: %w", err)
 	}
 
 	return ctx, nil
 }
 
+func (c *defaultContext) Library() *Library {
+	return c.lib
+}
+
+func (c *defaultContext) CopyString(str uintptr) string {
+	return goStr(str)
+}
+
+func (c *defaultContext) CopyError(gerr uintptr) *GError {
+	if gerr == NULLPTR {
+		return nil
+	}
+
+	raw := (*struct {
+		domain  uint32
+		code    int32
+		message uintptr
+	})(*(*unsafe.Pointer)(unsafe.Pointer(&gerr)))
+	domain, _, _ := purego.SyscallN(c.gQuarkToString, uintptr(raw.domain))
+	return &GError{
+		Domain:  goStr(domain),
+		Code:    int(raw.code),
+		Message: goStr(raw.message),
+	}
+}
+
+func (c *defaultContext) CopyEventConfigure(event uintptr) GdkEventConfigure {
+	raw := (*struct {
+		typ       int32
+		window    uintptr
+		sendEvent int8
+		x         int32
+		y         int32
+		width     int32
+		height    int32
+	})(*(*unsafe.Pointer)(unsafe.Pointer(&event)))
+	return GdkEventConfigure{
+		X:      raw.x,
+		Y:      raw.y,
+		Width:  raw.width,
+		Height: raw.height,
+	}
+}
+
+func (c *defaultContext) CopyEventWindowState(event uintptr) GdkEventWindowState {
+	raw := (*struct {
+		typ            int32
+		window         uintptr
+		sendEvent      int8
+		changedMask    uint32
+		newWindowState uint32
+	})(*(*unsafe.Pointer)(unsafe.Pointer(&event)))
+	return GdkEventWindowState{
+		ChangedMask:    GdkWindowState(raw.changedMask),
+		NewWindowState: GdkWindowState(raw.newWindowState),
+	}
+}
+
 func (c *defaultContext) GFree(mem uintptr) {
 	purego.SyscallN(c.gFree, mem)
 }
 
 func (c *defaultContext) GIdleAddFull(priority int, function GSourceFunc, data uintptr, notify GDestroyNotify) {
-	var destroyCb uintptr = NULLPTR
-	if notify != nil {
-		destroyCb = purego.NewCallback(notify)
-	}
+	initTrampolines()
+	handle := callbacks.register(&sourceFuncEntry{
+		function: function,
+		data:     data,
+		notify:   notify,
+	})
 
-	purego.SyscallN(c.gIdleAddFull, uintptr(priority), purego.NewCallback(function), data, destroyCb)
+	purego.SyscallN(c.gIdleAddFull, uintptr(priority), trampolines.sourceFunc, handle, trampolines.destroyNotify)
 }
 
+// GSignalConnectData connects cHandler, which must be a function of the form
+// func(instance, args..., userData) matching the C signature of the signal.
+// The handler is released when the signal is disconnected or the instance is
+// finalized.
 func (c *defaultContext) GSignalConnectData(instance GtkWidget, detailedSignal string, cHandler GCallback, data uintptr, destroyData GClosureNotify, connectFlags GConnectFlags) uint32 {
 	cstrDetailedSignal, free := cStr(detailedSignal)
 	defer free()
 
-	var destroyCb uintptr = NULLPTR
-	if destroyData != nil {
-		destroyCb = purego.NewCallback(destroyData)
+	entry, err := newSignalEntry(cHandler, data, destroyData)
+	if err != nil {
+		panic(fmt.Errorf("failed to connect %s: %w", detailedSignal, err))
 	}
 
-	ret, _, _ := purego.SyscallN(c.gSignalConnectData, uintptr(instance), uintptr(unsafe.Pointer(cstrDetailedSignal)), purego.NewCallback(cHandler), data, destroyCb, uintptr(connectFlags))
+	initTrampolines()
+	handle := callbacks.register(entry)
+
+	ret, _, _ := purego.SyscallN(c.gSignalConnectData, uintptr(instance), uintptr(unsafe.Pointer(cstrDetailedSignal)), trampolines.signal, handle, trampolines.closureNotify, uintptr(connectFlags|G_CONNECT_SWAPPED))
 	return uint32(ret)
 }
 
+func (c *defaultContext) GSignalHandlerDisconnect(instance GtkWidget, handlerID uint32) {
+	purego.SyscallN(c.gSignalHandlerDisconnect, uintptr(instance), uintptr(handlerID))
+}
+
+func (c *defaultContext) GCancellableNew() GCancellable {
+	ret, _, _ := purego.SyscallN(c.gCancellableNew)
+	return GCancellable(ret)
+}
+
+func (c *defaultContext) GCancellableCancel(cancellable GCancellable) {
+	purego.SyscallN(c.gCancellableCancel, uintptr(cancellable))
+}
+
+func (c *defaultContext) GObjectRef(object GObject) {
+	purego.SyscallN(c.gObjectRef, uintptr(object))
+}
+
+func (c *defaultContext) GObjectUnref(object GObject) {
+	purego.SyscallN(c.gObjectUnref, uintptr(object))
+}
+
+func (c *defaultContext) GInputStreamReadAll(stream GInputStream, buffer []byte) (int, error) {
+	if len(buffer) == 0 {
+		return 0, nil
+	}
+
+	var bytesRead uintptr
+	var gerr uintptr
+	ret, _, _ := purego.SyscallN(c.gInputStreamReadAll, uintptr(stream), uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)), uintptr(unsafe.Pointer(&bytesRead)), NULLPTR, uintptr(unsafe.Pointer(&gerr)))
+	if byte(ret) == 0 {
+		return int(bytesRead), c.takeError(gerr)
+	}
+	return int(bytesRead), nil
+}
+
+func (c *defaultContext) GUnixInputStreamNew(fd int, closeFd bool) GInputStream {
+	ret, _, _ := purego.SyscallN(c.gUnixInputStreamNew, uintptr(fd), uintptr(boolToInt(closeFd)))
+	return GInputStream(ret)
+}
+
+// GdkPixbufNewFromBytes decodes an image in any format supported by
+// gdk-pixbuf, such as PNG. The pixbuf must be released with GObjectUnref.
+func (c *defaultContext) GdkPixbufNewFromBytes(data []byte) (GdkPixbuf, error) {
+	if len(data) == 0 {
+		return GdkPixbuf(NULLPTR), fmt.Errorf("failed to load image: no data")
+	}
+
+	loader, _, _ := purego.SyscallN(c.gdkPixbufLoaderNew)
+	defer purego.SyscallN(c.gObjectUnref, loader)
+
+	var gerr uintptr
+	ret, _, _ := purego.SyscallN(c.gdkPixbufLoaderWrite, loader, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), uintptr(unsafe.Pointer(&gerr)))
+	runtime.KeepAlive(data)
+	if byte(ret) == 0 {
+		// The loader must be closed even if it failed.
+		purego.SyscallN(c.gdkPixbufLoaderClose, loader, NULLPTR)
+		return GdkPixbuf(NULLPTR), c.takeError(gerr)
+	}
+	ret, _, _ = purego.SyscallN(c.gdkPixbufLoaderClose, loader, uintptr(unsafe.Pointer(&gerr)))
+	if byte(ret) == 0 {
+		return GdkPixbuf(NULLPTR), c.takeError(gerr)
+	}
+
+	// The pixbuf is owned by the loader.
+	pixbuf, _, _ := purego.SyscallN(c.gdkPixbufLoaderGetPixbuf, loader)
+	if pixbuf == NULLPTR {
+		return GdkPixbuf(NULLPTR), fmt.Errorf("failed to load image: no image data")
+	}
+	purego.SyscallN(c.gObjectRef, pixbuf)
+	return GdkPixbuf(pixbuf), nil
+}
+
 func (c *defaultContext) GtkContainerAdd(container GtkContainer, widget GtkWidget) {
 	purego.SyscallN(c.gtkContainerAdd, uintptr(container), uintptr(widget))
 }
 
+func (c *defaultContext) GtkContainerRemove(container GtkContainer, widget GtkWidget) {
+	purego.SyscallN(c.gtkContainerRemove, uintptr(container), uintptr(widget))
+}
+
 func (c *defaultContext) GtkInitCheck() bool {
 	ret, _, _ := purego.SyscallN(c.gtkInitCheck)
 	return byte(ret) != 0
@@ -117,10 +388,89 @@ func (c *defaultContext) GtkWindowNew(windowType GtkWindowType) GtkWidget {
 	return GtkWidget(ret)
 }
 
+func (c *defaultContext) GtkWindowDestroy(window GtkWindow) {
+	purego.SyscallN(c.gtkWidgetDestroy, uintptr(window))
+}
+
 func (c *defaultContext) GtkWindowResize(window GtkWindow, width, height int) {
 	purego.SyscallN(c.gtkWindowResize, uintptr(window), uintptr(width), uintptr(height))
 }
 
+func (c *defaultContext) GtkWindowSetDefaultSize(window GtkWindow, width, height int) {
+	purego.SyscallN(c.gtkWindowSetDefaultSize, uintptr(window), uintptr(width), uintptr(height))
+}
+
+func (c *defaultContext) GtkWindowSetPosition(window GtkWindow, position GtkWindowPosition) {
+	purego.SyscallN(c.gtkWindowSetPosition, uintptr(window), uintptr(position))
+}
+
+func (c *defaultContext) GtkWindowSetIcon(window GtkWindow, icon GdkPixbuf) {
+	purego.SyscallN(c.gtkWindowSetIcon, uintptr(window), uintptr(icon))
+}
+
+func (c *defaultContext) GtkWindowGetSize(window GtkWindow) (width, height int) {
+	var w, h int32
+	purego.SyscallN(c.gtkWindowGetSize, uintptr(window), uintptr(unsafe.Pointer(&w)), uintptr(unsafe.Pointer(&h)))
+	return int(w), int(h)
+}
+
+func (c *defaultContext) GtkWindowMove(window GtkWindow, x, y int) {
+	purego.SyscallN(c.gtkWindowMove, uintptr(window), uintptr(x), uintptr(y))
+}
+
+func (c *defaultContext) GtkWindowGetPosition(window GtkWindow) (x, y int) {
+	var rootX, rootY int32
+	purego.SyscallN(c.gtkWindowGetPosition, uintptr(window), uintptr(unsafe.Pointer(&rootX)), uintptr(unsafe.Pointer(&rootY)))
+	return int(rootX), int(rootY)
+}
+
+func (c *defaultContext) GtkWindowMaximize(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowMaximize, uintptr(window))
+}
+
+func (c *defaultContext) GtkWindowUnmaximize(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowUnmaximize, uintptr(window))
+}
+
+func (c *defaultContext) GtkWindowIconify(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowIconify, uintptr(window))
+}
+
+func (c *defaultContext) GtkWindowFullscreen(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowFullscreen, uintptr(window))
+}
+
+func (c *defaultContext) GtkWindowUnfullscreen(window GtkWindow) {
+	purego.SyscallN(c.gtkWindowUnfullscreen, uintptr(window))
+}
+
+func (c *defaultContext) GtkWindowSetKeepAbove(window GtkWindow, setting bool) {
+	purego.SyscallN(c.gtkWindowSetKeepAbove, uintptr(window), uintptr(boolToInt(setting)))
+}
+
+func (c *defaultContext) GtkWindowSetDecorated(window GtkWindow, setting bool) {
+	purego.SyscallN(c.gtkWindowSetDecorated, uintptr(window), uintptr(boolToInt(setting)))
+}
+
+func (c *defaultContext) GtkWindowIsActive(window GtkWindow) bool {
+	ret, _, _ := purego.SyscallN(c.gtkWindowIsActive, uintptr(window))
+	return uint32(ret) != 0
+}
+
+func (c *defaultContext) GtkWindowIsMaximized(window GtkWindow) bool {
+	ret, _, _ := purego.SyscallN(c.gtkWindowIsMaximized, uintptr(window))
+	return uint32(ret) != 0
+}
+
+// GtkWindowIsFullscreen returns false before GTK 3.24.
+func (c *defaultContext) GtkWindowIsFullscreen(window GtkWindow) bool {
+	if c.gtkWindowIsFullscreen == NULLPTR {
+		return false
+	}
+	ret, _, _ := purego.SyscallN(c.gtkWindowIsFullscreen, uintptr(window))
+	return uint32(ret) != 0
+}
+
 func (c *defaultContext) GtkWindowSetGeometryHints(window GtkWindow, geometryWidget GtkWidget, geometry GdkGeometry, geomMask GdkWindowHints) {
 	purego.SyscallN(c.gtkWindowSetGeometryHints, uintptr(window), uintptr(geometryWidget), uintptr(unsafe.Pointer(&geometry)), uintptr(geomMask))
 }
@@ -143,6 +493,19 @@ func (c *defaultContext) JsCValueToString(value JSCValue) string {
 	return str
 }
 
+// JsCValueToJSON returns an empty string if value can't be represented as
+// JSON, e.g. for undefined. On JavaScriptCore < 2.28 strings are returned
+// unquoted.
+func (c *defaultContext) JsCValueToJSON(value JSCValue, indent uint) string {
+	if c.jsCValueToJSON == NULLPTR {
+		return c.JsCValueToString(value)
+	}
+	ret, _, _ := purego.SyscallN(c.jsCValueToJSON, uintptr(value), uintptr(indent))
+	str := goStr(ret)
+	c.GFree(ret)
+	return str
+}
+
 func (c *defaultContext) WebKitGetMajorVersion() uint32 {
 	ret, _, _ := purego.SyscallN(c.webKitGetMajorVersion)
 	return uint32(ret)
@@ -163,6 +526,125 @@ func (c *defaultContext) WebKitWebViewNew() GtkWidget {
 	return GtkWidget(ret)
 }
 
+func (c *defaultContext) WebKitNetworkSessionGetDefault() WebKitNetworkSession {
+	ret, _, _ := purego.SyscallN(c.webKitWebContextGetDefault)
+	return WebKitNetworkSession(ret)
+}
+
+func (c *defaultContext) WebKitNetworkSessionNew(dataDirectory string, cacheDirectory string) WebKitNetworkSession {
+	cstrDataKey, free := cStr("base-data-directory")
+	defer free()
+	cstrDataDirectory, free := cStr(dataDirectory)
+	defer free()
+	cstrCacheKey, free := cStr("base-cache-directory")
+	defer free()
+	cstrCacheDirectory, free := cStr(cacheDirectory)
+	defer free()
+
+	manager, _, _ := purego.SyscallN(c.webKitWebsiteDataManagerNew, uintptr(unsafe.Pointer(cstrDataKey)), uintptr(unsafe.Pointer(cstrDataDirectory)), uintptr(unsafe.Pointer(cstrCacheKey)), uintptr(unsafe.Pointer(cstrCacheDirectory)), NULLPTR)
+	defer purego.SyscallN(c.gObjectUnref, manager)
+
+	ret, _, _ := purego.SyscallN(c.webKitWebContextNewWithWebsiteDataManager, manager)
+	return WebKitNetworkSession(ret)
+}
+
+func (c *defaultContext) WebKitNetworkSessionNewEphemeral() WebKitNetworkSession {
+	ret, _, _ := purego.SyscallN(c.webKitWebContextNewEphemeral)
+	return WebKitNetworkSession(ret)
+}
+
+func (c *defaultContext) WebKitNetworkSessionGetCookieManager(session WebKitNetworkSession) WebKitCookieManager {
+	ret, _, _ := purego.SyscallN(c.webKitWebContextGetCookieManager, uintptr(session))
+	return WebKitCookieManager(ret)
+}
+
+func (c *defaultContext) WebKitWebViewNewWithNetworkSession(session WebKitNetworkSession) GtkWidget {
+	ret, _, _ := purego.SyscallN(c.webKitWebViewNewWithContext, uintptr(session))
+	return GtkWidget(ret)
+}
+
+func (c *defaultContext) WebKitNetworkSessionGetWebsiteDataManager(session WebKitNetworkSession) WebKitWebsiteDataManager {
+	ret, _, _ := purego.SyscallN(c.webKitWebContextGetWebsiteDataManager, uintptr(session))
+	return WebKitWebsiteDataManager(ret)
+}
+
+func (c *defaultContext) WebKitWebsiteDataManagerClear(manager WebKitWebsiteDataManager, types WebKitWebsiteDataTypes, timespan int64, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr) {
+	callbackCb, handle := registerAsyncReady(callback, userData)
+	purego.SyscallN(c.webKitWebsiteDataManagerClear, uintptr(manager), uintptr(types), uintptr(timespan), uintptr(cancellable), callbackCb, handle)
+}
+
+func (c *defaultContext) WebKitWebsiteDataManagerClearFinish(manager WebKitWebsiteDataManager, result GAsyncResult) error {
+	return c.finishBool(c.webKitWebsiteDataManagerClearFinish, uintptr(manager), result)
+}
+
+func (c *defaultContext) WebKitCookieManagerSetAcceptPolicy(manager WebKitCookieManager, policy WebKitCookieAcceptPolicy) {
+	purego.SyscallN(c.webKitCookieManagerSetAcceptPolicy, uintptr(manager), uintptr(policy))
+}
+
+func (c *defaultContext) WebKitCookieManagerGetCookies(manager WebKitCookieManager, uri string, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr) {
+	cstrURI, free := cStr(uri)
+	defer free()
+	callbackCb, handle := registerAsyncReady(callback, userData)
+	purego.SyscallN(c.webKitCookieManagerGetCookies, uintptr(manager), uintptr(unsafe.Pointer(cstrURI)), uintptr(cancellable), callbackCb, handle)
+}
+
+// WebKitCookieManagerGetCookiesFinish copies and frees the returned list of
+// cookies.
+func (c *defaultContext) WebKitCookieManagerGetCookiesFinish(manager WebKitCookieManager, result GAsyncResult) ([]SoupCookieData, error) {
+	var gerr uintptr
+	list, _, _ := purego.SyscallN(c.webKitCookieManagerGetCookiesFinish, uintptr(manager), uintptr(result), uintptr(unsafe.Pointer(&gerr)))
+	if gerr != NULLPTR {
+		return nil, c.takeError(gerr)
+	}
+	defer purego.SyscallN(c.gListFreeFull, list, c.soupCookieFree)
+
+	var cookies []SoupCookieData
+	for node := list; node != NULLPTR; {
+		raw := (*struct {
+			data uintptr
+			next uintptr
+			prev uintptr
+		})(*(*unsafe.Pointer)(unsafe.Pointer(&node)))
+		cookies = append(cookies, c.copyCookie(raw.data))
+		node = raw.next
+	}
+	return cookies, nil
+}
+
+func (c *defaultContext) WebKitCookieManagerAddCookie(manager WebKitCookieManager, cookie SoupCookie, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr) {
+	callbackCb, handle := registerAsyncReady(callback, userData)
+	purego.SyscallN(c.webKitCookieManagerAddCookie, uintptr(manager), uintptr(cookie), uintptr(cancellable), callbackCb, handle)
+}
+
+func (c *defaultContext) WebKitCookieManagerAddCookieFinish(manager WebKitCookieManager, result GAsyncResult) error {
+	return c.finishBool(c.webKitCookieManagerAddCookieFinish, uintptr(manager), result)
+}
+
+func (c *defaultContext) WebKitCookieManagerDeleteCookie(manager WebKitCookieManager, cookie SoupCookie, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr) {
+	callbackCb, handle := registerAsyncReady(callback, userData)
+	purego.SyscallN(c.webKitCookieManagerDeleteCookie, uintptr(manager), uintptr(cookie), uintptr(cancellable), callbackCb, handle)
+}
+
+func (c *defaultContext) WebKitCookieManagerDeleteCookieFinish(manager WebKitCookieManager, result GAsyncResult) error {
+	return c.finishBool(c.webKitCookieManagerDeleteCookieFinish, uintptr(manager), result)
+}
+
+// finishBool calls a finish function returning a gboolean and a GError.
+func (c *defaultContext) finishBool(finish uintptr, object uintptr, result GAsyncResult) error {
+	var gerr uintptr
+	ret, _, _ := purego.SyscallN(finish, object, uintptr(result), uintptr(unsafe.Pointer(&gerr)))
+	if byte(ret) == 0 {
+		return c.takeError(gerr)
+	}
+	return nil
+}
+
+func (c *defaultContext) WebKitCookieManagerSetPersistentStorage(manager WebKitCookieManager, filename string, storage WebKitCookiePersistentStorage) {
+	cstrFilename, free := cStr(filename)
+	defer free()
+	purego.SyscallN(c.webKitCookieManagerSetPersistentStorage, uintptr(manager), uintptr(unsafe.Pointer(cstrFilename)), uintptr(storage))
+}
+
 func (c *defaultContext) WebKitWebViewGetUserContentManager(webview WebKitWebView) WebKitUserContentManager {
 	ret, _, _ := purego.SyscallN(c.webKitWebViewGetUserContentManager, uintptr(webview))
 	return WebKitUserContentManager(ret)
@@ -191,16 +673,109 @@ func (c *defaultContext) WebKitWebViewLoadHTML(webview WebKitWebView, content st
 	purego.SyscallN(c.webKitWebViewLoadHTML, uintptr(webview), uintptr(unsafe.Pointer(cstrContent)), baseUriPtr)
 }
 
+func (c *defaultContext) WebKitWebViewLoadAlternateHTML(webview WebKitWebView, content string, contentUri string, baseUri string) {
+	cstrContent, free := cStr(content)
+	defer free()
+	cstrContentUri, free := cStr(contentUri)
+	defer free()
+	baseUriPtr := NULLPTR
+	if baseUri != "" {
+		cstrBaseUri, free := cStr(baseUri)
+		defer free()
+		baseUriPtr = uintptr(unsafe.Pointer(cstrBaseUri))
+	}
+	purego.SyscallN(c.webKitWebViewLoadAlternateHTML, uintptr(webview), uintptr(unsafe.Pointer(cstrContent)), uintptr(unsafe.Pointer(cstrContentUri)), baseUriPtr)
+}
+
+func (c *defaultContext) WebKitWebViewGetURI(webview WebKitWebView) string {
+	ret, _, _ := purego.SyscallN(c.webKitWebViewGetURI, uintptr(webview))
+	return goStr(ret)
+}
+
+func (c *defaultContext) WebKitWebViewGetMainResource(webview WebKitWebView) WebKitWebResource {
+	ret, _, _ := purego.SyscallN(c.webKitWebViewGetMainResource, uintptr(webview))
+	return WebKitWebResource(ret)
+}
+
+func (c *defaultContext) WebKitWebResourceGetResponse(resource WebKitWebResource) WebKitURIResponse {
+	ret, _, _ := purego.SyscallN(c.webKitWebResourceGetResponse, uintptr(resource))
+	return WebKitURIResponse(ret)
+}
+
+func (c *defaultContext) WebKitURIResponseGetStatusCode(response WebKitURIResponse) uint {
+	ret, _, _ := purego.SyscallN(c.webKitURIResponseGetStatusCode, uintptr(response))
+	return uint(uint32(ret))
+}
+
+func (c *defaultContext) WebKitURIResponseGetMIMEType(response WebKitURIResponse) string {
+	ret, _, _ := purego.SyscallN(c.webKitURIResponseGetMIMEType, uintptr(response))
+	return goStr(ret)
+}
+
+func (c *defaultContext) WebKitURIRequestGetURI(request WebKitURIRequest) string {
+	ret, _, _ := purego.SyscallN(c.webKitURIRequestGetURI, uintptr(request))
+	return goStr(ret)
+}
+
+func (c *defaultContext) WebKitPolicyDecisionUse(decision WebKitPolicyDecision) {
+	purego.SyscallN(c.webKitPolicyDecisionUse, uintptr(decision))
+}
+
+func (c *defaultContext) WebKitPolicyDecisionIgnore(decision WebKitPolicyDecision) {
+	purego.SyscallN(c.webKitPolicyDecisionIgnore, uintptr(decision))
+}
+
+func (c *defaultContext) WebKitNavigationPolicyDecisionGetNavigationAction(decision WebKitPolicyDecision) WebKitNavigationAction {
+	ret, _, _ := purego.SyscallN(c.webKitNavigationPolicyDecisionGetNavigationAction, uintptr(decision))
+	return WebKitNavigationAction(ret)
+}
+
+func (c *defaultContext) WebKitNavigationActionGetRequest(action WebKitNavigationAction) WebKitURIRequest {
+	ret, _, _ := purego.SyscallN(c.webKitNavigationActionGetRequest, uintptr(action))
+	return WebKitURIRequest(ret)
+}
+
+func (c *defaultContext) WebKitNavigationActionIsUserGesture(action WebKitNavigationAction) bool {
+	ret, _, _ := purego.SyscallN(c.webKitNavigationActionIsUserGesture, uintptr(action))
+	return uint32(ret) != 0
+}
+
+func (c *defaultContext) WebKitResponsePolicyDecisionGetRequest(decision WebKitPolicyDecision) WebKitURIRequest {
+	ret, _, _ := purego.SyscallN(c.webKitResponsePolicyDecisionGetRequest, uintptr(decision))
+	return WebKitURIRequest(ret)
+}
+
+func (c *defaultContext) WebKitResponsePolicyDecisionGetResponse(decision WebKitPolicyDecision) WebKitURIResponse {
+	ret, _, _ := purego.SyscallN(c.webKitResponsePolicyDecisionGetResponse, uintptr(decision))
+	return WebKitURIResponse(ret)
+}
+
+// WebKitResponsePolicyDecisionIsMainFrameMainResource requires WebKitGTK 2.40
+// and returns true on older versions.
+func (c *defaultContext) WebKitResponsePolicyDecisionIsMainFrameMainResource(decision WebKitPolicyDecision) bool {
+	if c.webKitResponsePolicyDecisionIsMainFrameMainResource == NULLPTR {
+		return true
+	}
+	ret, _, _ := purego.SyscallN(c.webKitResponsePolicyDecisionIsMainFrameMainResource, uintptr(decision))
+	return byte(ret) != 0
+}
+
 func (c *defaultContext) WebKitWebViewRunJavascript(webview WebKitWebView, script string, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr) {
 	cstrScript, free := cStr(script)
 	defer free()
 
-	var callbackCb uintptr = NULLPTR
-	if callback != nil {
-		callbackCb = purego.NewCallback(callback)
-	}
+	callbackCb, handle := registerAsyncReady(callback, userData)
 
-	purego.SyscallN(c.webKitWebViewRunJavascript, uintptr(webview), uintptr(unsafe.Pointer(cstrScript)), uintptr(cancellable), callbackCb, userData)
+	purego.SyscallN(c.webKitWebViewRunJavascript, uintptr(webview), uintptr(unsafe.Pointer(cstrScript)), uintptr(cancellable), callbackCb, handle)
+}
+
+func (c *defaultContext) WebKitWebViewRunJavascriptFinish(webview WebKitWebView, result GAsyncResult) (WebKitJavascriptResult, error) {
+	var gerr uintptr
+	ret, _, _ := purego.SyscallN(c.webKitWebViewRunJavascriptFinish, uintptr(webview), uintptr(result), uintptr(unsafe.Pointer(&gerr)))
+	if ret == NULLPTR {
+		return WebKitJavascriptResult(NULLPTR), c.takeError(gerr)
+	}
+	return WebKitJavascriptResult(ret), nil
 }
 
 func (c *defaultContext) WebKitJavascriptResultGetJsValue(jsResult WebKitJavascriptResult) JSCValue {
@@ -208,35 +783,48 @@ func (c *defaultContext) WebKitJavascriptResultGetJsValue(jsResult WebKitJavascr
 	return JSCValue(ret)
 }
 
+func (c *defaultContext) WebKitJavascriptResultUnref(jsResult WebKitJavascriptResult) {
+	purego.SyscallN(c.webKitJavascriptResultUnref, uintptr(jsResult))
+}
+
 func (c *defaultContext) WebKitUserContentManagerAddScript(manager WebKitUserContentManager, script WebKitUserScript) {
 	purego.SyscallN(c.webKitUserContentManagerAddScript, uintptr(manager), uintptr(script))
 }
 
+func (c *defaultContext) WebKitUserContentManagerRemoveAllScripts(manager WebKitUserContentManager) {
+	purego.SyscallN(c.webKitUserContentManagerRemoveAllScripts, uintptr(manager))
+}
+
+func (c *defaultContext) WebKitUserContentManagerUnregisterScriptMessageHandler(manager WebKitUserContentManager, name string) {
+	cstrName, free := cStr(name)
+	defer free()
+	purego.SyscallN(c.webKitUserContentManagerUnregisterScriptMessageHandler, uintptr(manager), uintptr(unsafe.Pointer(cstrName)))
+}
+
 func (c *defaultContext) WebKitUserContentManagerRegisterScriptMessageHandler(manager WebKitUserContentManager, name string) {
 	cstrName, free := cStr(name)
 	defer free()
 	purego.SyscallN(c.webKitUserContentManagerRegisterScriptMessageHandler, uintptr(manager), uintptr(unsafe.Pointer(cstrName)))
 }
 
-func (c *defaultContext) WebKitUserScriptNew(source string, injectedFrames WebKitUserContentInjectedFrames, injectionTime WebKitUserScriptInjectionTime, whitelist string, blacklist string) WebKitUserScript {
+// WebKitUserScriptNew creates a user script. The allow and block lists hold
+// URL patterns like "https://*.example.com/*", an empty allow list matches
+// every URL.
+func (c *defaultContext) WebKitUserScriptNew(source string, injectedFrames WebKitUserContentInjectedFrames, injectionTime WebKitUserScriptInjectionTime, allowList []string, blockList []string) WebKitUserScript {
 	cstrSource, free := cStr(source)
 	defer free()
-	cstrWhitelist, free := cStr(whitelist)
+	allowListPtr, free := cStrArray(allowList)
 	defer free()
-	whitelistPtr := NULLPTR
-	if whitelist != "" {
-		whitelistPtr = uintptr(unsafe.Pointer(cstrWhitelist))
-	}
-	cstrBlacklist, free := cStr(blacklist)
+	blockListPtr, free := cStrArray(blockList)
 	defer free()
-	blacklistPtr := NULLPTR
-	if blacklist != "" {
-		blacklistPtr = uintptr(unsafe.Pointer(cstrBlacklist))
-	}
-	ret, _, _ := purego.SyscallN(c.webKitUserScriptNew, uintptr(unsafe.Pointer(cstrSource)), uintptr(injectedFrames), uintptr(injectionTime), whitelistPtr, blacklistPtr)
+	ret, _, _ := purego.SyscallN(c.webKitUserScriptNew, uintptr(unsafe.Pointer(cstrSource)), uintptr(injectedFrames), uintptr(injectionTime), allowListPtr, blockListPtr)
 	return WebKitUserScript(ret)
 }
 
+func (c *defaultContext) WebKitUserScriptUnref(script WebKitUserScript) {
+	purego.SyscallN(c.webKitUserScriptUnref, uintptr(script))
+}
+
 func (c *defaultContext) WebKitSettingsSetEnableDeveloperExtras(settings WebKitSettings, enabled bool) {
 	purego.SyscallN(c.webKitSettingsSetEnableDeveloperExtras, uintptr(settings), uintptr(boolToInt(enabled)))
 }
@@ -249,50 +837,406 @@ func (c *defaultContext) WebKitSettingsSetJavascriptCanAccessClipboard(settings
 	purego.SyscallN(c.webKitSettingsSetJavascriptCanAccessClipboard, uintptr(settings), uintptr(boolToInt(enabled)))
 }
 
+func (c *defaultContext) WebKitWebViewGetContext(webview WebKitWebView) WebKitWebContext {
+	ret, _, _ := purego.SyscallN(c.webKitWebViewGetContext, uintptr(webview))
+	return WebKitWebContext(ret)
+}
+
+func (c *defaultContext) WebKitWebContextRegisterURIScheme(context WebKitWebContext, scheme string, callback WebKitURISchemeRequestCallback, userData uintptr, notify GDestroyNotify) {
+	cstrScheme, free := cStr(scheme)
+	defer free()
+
+	initTrampolines()
+	handle := callbacks.register(&uriSchemeEntry{
+		callback: callback,
+		data:     userData,
+		notify:   notify,
+	})
+
+	purego.SyscallN(c.webKitWebContextRegisterURIScheme, uintptr(context), uintptr(unsafe.Pointer(cstrScheme)), trampolines.uriScheme, handle, trampolines.destroyNotify)
+}
+
+func (c *defaultContext) WebKitWebContextGetSecurityManager(context WebKitWebContext) WebKitSecurityManager {
+	ret, _, _ := purego.SyscallN(c.webKitWebContextGetSecurityManager, uintptr(context))
+	return WebKitSecurityManager(ret)
+}
+
+func (c *defaultContext) WebKitSecurityManagerRegisterURISchemeAsSecure(manager WebKitSecurityManager, scheme string) {
+	cstrScheme, free := cStr(scheme)
+	defer free()
+	purego.SyscallN(c.webKitSecurityManagerRegisterURISchemeAsSecure, uintptr(manager), uintptr(unsafe.Pointer(cstrScheme)))
+}
+
+func (c *defaultContext) WebKitSecurityManagerRegisterURISchemeAsCorsEnabled(manager WebKitSecurityManager, scheme string) {
+	cstrScheme, free := cStr(scheme)
+	defer free()
+	purego.SyscallN(c.webKitSecurityManagerRegisterURISchemeAsCorsEnabled, uintptr(manager), uintptr(unsafe.Pointer(cstrScheme)))
+}
+
+func (c *defaultContext) WebKitURISchemeRequestGetURI(request WebKitURISchemeRequest) string {
+	ret, _, _ := purego.SyscallN(c.webKitURISchemeRequestGetURI, uintptr(request))
+	return goStr(ret)
+}
+
+func (c *defaultContext) WebKitURISchemeRequestGetWebView(request WebKitURISchemeRequest) WebKitWebView {
+	ret, _, _ := purego.SyscallN(c.webKitURISchemeRequestGetWebView, uintptr(request))
+	return WebKitWebView(ret)
+}
+
+// WebKitURISchemeRequestGetHTTPMethod requires WebKitGTK 2.36 and returns an
+// empty string on older versions.
+func (c *defaultContext) WebKitURISchemeRequestGetHTTPMethod(request WebKitURISchemeRequest) string {
+	if c.webKitURISchemeRequestGetHTTPMethod == NULLPTR {
+		return ""
+	}
+	ret, _, _ := purego.SyscallN(c.webKitURISchemeRequestGetHTTPMethod, uintptr(request))
+	return goStr(ret)
+}
+
+// WebKitURISchemeRequestGetHTTPHeaders requires WebKitGTK 2.36 and returns
+// NULLPTR on older versions.
+func (c *defaultContext) WebKitURISchemeRequestGetHTTPHeaders(request WebKitURISchemeRequest) SoupMessageHeaders {
+	if c.webKitURISchemeRequestGetHTTPHeaders == NULLPTR {
+		return SoupMessageHeaders(NULLPTR)
+	}
+	ret, _, _ := purego.SyscallN(c.webKitURISchemeRequestGetHTTPHeaders, uintptr(request))
+	return SoupMessageHeaders(ret)
+}
+
+// WebKitURISchemeRequestGetHTTPBody requires WebKitGTK 2.40 and returns
+// NULLPTR on older versions. The returned stream must be unreferenced.
+func (c *defaultContext) WebKitURISchemeRequestGetHTTPBody(request WebKitURISchemeRequest) GInputStream {
+	if c.webKitURISchemeRequestGetHTTPBody == NULLPTR {
+		return GInputStream(NULLPTR)
+	}
+	ret, _, _ := purego.SyscallN(c.webKitURISchemeRequestGetHTTPBody, uintptr(request))
+	return GInputStream(ret)
+}
+
+func (c *defaultContext) WebKitURISchemeRequestFinish(request WebKitURISchemeRequest, stream GInputStream, length int64, contentType string) {
+	contentTypePtr := NULLPTR
+	if contentType != "" {
+		cstrContentType, free := cStr(contentType)
+		defer free()
+		contentTypePtr = uintptr(unsafe.Pointer(cstrContentType))
+	}
+	purego.SyscallN(c.webKitURISchemeRequestFinish, uintptr(request), uintptr(stream), uintptr(length), contentTypePtr)
+}
+
+func (c *defaultContext) WebKitURISchemeRequestFinishWithResponse(request WebKitURISchemeRequest, response WebKitURISchemeResponse) {
+	purego.SyscallN(c.webKitURISchemeRequestFinishWithResponse, uintptr(request), uintptr(response))
+}
+
+func (c *defaultContext) WebKitURISchemeRequestFinishError(request WebKitURISchemeRequest, message string) {
+	gerr := c.newError(message)
+	defer purego.SyscallN(c.gErrorFree, gerr)
+	purego.SyscallN(c.webKitURISchemeRequestFinishError, uintptr(request), gerr)
+}
+
+// WebKitURISchemeResponseNew requires WebKitGTK 2.36 and returns NULLPTR on
+// older versions, in which case WebKitURISchemeRequestFinish must be used.
+func (c *defaultContext) WebKitURISchemeResponseNew(stream GInputStream, length int64) WebKitURISchemeResponse {
+	if c.webKitURISchemeResponseNew == NULLPTR {
+		return WebKitURISchemeResponse(NULLPTR)
+	}
+	ret, _, _ := purego.SyscallN(c.webKitURISchemeResponseNew, uintptr(stream), uintptr(length))
+	return WebKitURISchemeResponse(ret)
+}
+
+func (c *defaultContext) WebKitURISchemeResponseSetStatus(response WebKitURISchemeResponse, statusCode int, reasonPhrase string) {
+	reasonPhrasePtr := NULLPTR
+	if reasonPhrase != "" {
+		cstrReasonPhrase, free := cStr(reasonPhrase)
+		defer free()
+		reasonPhrasePtr = uintptr(unsafe.Pointer(cstrReasonPhrase))
+	}
+	purego.SyscallN(c.webKitURISchemeResponseSetStatus, uintptr(response), uintptr(statusCode), reasonPhrasePtr)
+}
+
+func (c *defaultContext) WebKitURISchemeResponseSetContentType(response WebKitURISchemeResponse, contentType string) {
+	cstrContentType, free := cStr(contentType)
+	defer free()
+	purego.SyscallN(c.webKitURISchemeResponseSetContentType, uintptr(response), uintptr(unsafe.Pointer(cstrContentType)))
+}
+
+// WebKitURISchemeResponseSetHTTPHeaders takes ownership of headers.
+func (c *defaultContext) WebKitURISchemeResponseSetHTTPHeaders(response WebKitURISchemeResponse, headers SoupMessageHeaders) {
+	purego.SyscallN(c.webKitURISchemeResponseSetHTTPHeaders, uintptr(response), uintptr(headers))
+}
+
+// Soup
+func (c *defaultContext) SoupMessageHeadersNew(headersType SoupMessageHeadersType) SoupMessageHeaders {
+	ret, _, _ := purego.SyscallN(c.soupMessageHeadersNew, uintptr(headersType))
+	return SoupMessageHeaders(ret)
+}
+
+func (c *defaultContext) SoupMessageHeadersAppend(headers SoupMessageHeaders, name string, value string) {
+	cstrName, free := cStr(name)
+	defer free()
+	cstrValue, free := cStr(value)
+	defer free()
+	purego.SyscallN(c.soupMessageHeadersAppend, uintptr(headers), uintptr(unsafe.Pointer(cstrName)), uintptr(unsafe.Pointer(cstrValue)))
+}
+
+func (c *defaultContext) SoupMessageHeadersForeach(headers SoupMessageHeaders, f func(name string, value string)) {
+	initTrampolines()
+	handle := callbacks.register(&headersForeachEntry{f: f})
+	defer callbacks.release(handle)
+
+	purego.SyscallN(c.soupMessageHeadersForeach, uintptr(headers), trampolines.headersForeach, handle)
+}
+
+// SoupCookieNew creates a cookie that must be freed with SoupCookieFree.
+// libsoup only takes a max-age, so the expiry is converted to one.
+func (c *defaultContext) SoupCookieNew(cookie SoupCookieData) SoupCookie {
+	cstrName, free := cStr(cookie.Name)
+	defer free()
+	cstrValue, free := cStr(cookie.Value)
+	defer free()
+	cstrDomain, free := cStr(cookie.Domain)
+	defer free()
+	cstrPath, free := cStr(cookie.Path)
+	defer free()
+
+	maxAge := -1
+	if cookie.Expires != 0 {
+		maxAge = int(cookie.Expires - time.Now().Unix())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+	}
+
+	ret, _, _ := purego.SyscallN(c.soupCookieNew, uintptr(unsafe.Pointer(cstrName)), uintptr(unsafe.Pointer(cstrValue)), uintptr(unsafe.Pointer(cstrDomain)), uintptr(unsafe.Pointer(cstrPath)), uintptr(maxAge))
+	purego.SyscallN(c.soupCookieSetSecure, ret, uintptr(boolToInt(cookie.Secure)))
+	purego.SyscallN(c.soupCookieSetHTTPOnly, ret, uintptr(boolToInt(cookie.HTTPOnly)))
+	if c.soupCookieSetSameSite != NULLPTR {
+		purego.SyscallN(c.soupCookieSetSameSite, ret, uintptr(cookie.SameSite))
+	}
+	return SoupCookie(ret)
+}
+
+func (c *defaultContext) SoupCookieFree(cookie SoupCookie) {
+	purego.SyscallN(c.soupCookieFree, uintptr(cookie))
+}
+
+// copyCookie copies the fields of a SoupCookie*. The expiry is a SoupDate in
+// libsoup 2 and a GDateTime in libsoup 3.
+func (c *defaultContext) copyCookie(cookie uintptr) SoupCookieData {
+	name, _, _ := purego.SyscallN(c.soupCookieGetName, cookie)
+	value, _, _ := purego.SyscallN(c.soupCookieGetValue, cookie)
+	domain, _, _ := purego.SyscallN(c.soupCookieGetDomain, cookie)
+	path, _, _ := purego.SyscallN(c.soupCookieGetPath, cookie)
+	secure, _, _ := purego.SyscallN(c.soupCookieGetSecure, cookie)
+	httpOnly, _, _ := purego.SyscallN(c.soupCookieGetHTTPOnly, cookie)
+
+	data := SoupCookieData{
+		Name:     goStr(name),
+		Value:    goStr(value),
+		Domain:   goStr(domain),
+		Path:     goStr(path),
+		Secure:   byte(secure) != 0,
+		HTTPOnly: byte(httpOnly) != 0,
+	}
+
+	if expires, _, _ := purego.SyscallN(c.soupCookieGetExpires, cookie); expires != NULLPTR {
+		var t uintptr
+		if c.lib.ABI == ABI40 {
+			t, _, _ = purego.SyscallN(c.soupDateToTimeT, expires)
+		} else {
+			t, _, _ = purego.SyscallN(c.gDateTimeToUnix, expires)
+		}
+		data.Expires = int64(t)
+	}
+	if c.soupCookieGetSameSite != NULLPTR {
+		sameSite, _, _ := purego.SyscallN(c.soupCookieGetSameSite, cookie)
+		data.SameSite = SoupSameSitePolicy(sameSite)
+	}
+	return data
+}
+
+// newError creates a GError in the webview domain. It must be freed with
+// g_error_free.
+func (c *defaultContext) newError(message string) uintptr {
+	cstrDomain, free := cStr("webview")
+	defer free()
+	cstrMessage, free := cStr(message)
+	defer free()
+	domain, _, _ := purego.SyscallN(c.gQuarkFromString, uintptr(unsafe.Pointer(cstrDomain)))
+	ret, _, _ := purego.SyscallN(c.gErrorNewLiteral, domain, 0, uintptr(unsafe.Pointer(cstrMessage)))
+	return ret
+}
+
+// takeError converts a GError to a Go error and frees it.
+func (c *defaultContext) takeError(gerr uintptr) error {
+	if gerr == NULLPTR {
+		return nil
+	}
+
+	err := c.CopyError(gerr)
+	purego.SyscallN(c.gErrorFree, gerr)
+	return err
+}
+
 func (c *defaultContext) LoadFunctions() error {
 	g := &procAddressGetter{ctx: c}
+	c.loadFunctions(g)
+
+	// GTK 3 and WebKit2GTK only
+	c.gtkContainerAdd = g.get("gtk_container_add")
+	c.gtkContainerRemove = g.get("gtk_container_remove")
+	c.gtkMain = g.get("gtk_main")
+	c.gtkMainQuit = g.get("gtk_main_quit")
+	c.gtkWidgetShowAll = g.get("gtk_widget_show_all")
+	c.gtkWidgetDestroy = g.get("gtk_widget_destroy")
+	c.gtkWindowResize = g.get("gtk_window_resize")
+	c.gtkWindowSetPosition = g.get("gtk_window_set_position")
+	c.gtkWindowSetIcon = g.get("gtk_window_set_icon")
+	c.gtkWindowGetSize = g.get("gtk_window_get_size")
+	c.gtkWindowMove = g.get("gtk_window_move")
+	c.gtkWindowGetPosition = g.get("gtk_window_get_position")
+	c.gtkWindowIconify = g.get("gtk_window_iconify")
+	c.gtkWindowSetKeepAbove = g.get("gtk_window_set_keep_above")
+	c.gtkWindowSetGeometryHints = g.get("gtk_window_set_geometry_hints")
+	c.webKitWebViewRunJavascript = g.get("webkit_web_view_run_javascript")
+	c.webKitWebViewRunJavascriptFinish = g.get("webkit_web_view_run_javascript_finish")
+	c.webKitJavascriptResultGetJsValue = g.get("webkit_javascript_result_get_js_value")
+	c.webKitJavascriptResultUnref = g.get("webkit_javascript_result_unref")
+	c.webKitWebViewNewWithContext = g.get("webkit_web_view_new_with_context")
+	c.webKitWebContextGetDefault = g.get("webkit_web_context_get_default")
+	c.webKitWebContextNewEphemeral = g.get("webkit_web_context_new_ephemeral")
+	c.webKitWebContextNewWithWebsiteDataManager = g.get("webkit_web_context_new_with_website_data_manager")
+	c.webKitWebContextGetCookieManager = g.get("webkit_web_context_get_cookie_manager")
+	c.webKitWebsiteDataManagerNew = g.get("webkit_website_data_manager_new")
+	c.webKitWebContextGetWebsiteDataManager = g.get("webkit_web_context_get_website_data_manager")
+
+	if g.err != nil {
+		return fmt.Errorf("failed to load functions: %w", g.err)
+	}
 
+	return nil
+}
+
+// loadFunctions loads the functions shared by all supported ABIs.
+func (c *defaultContext) loadFunctions(g *procAddressGetter) {
 	// GTK
+	c.gCancellableCancel = g.get("g_cancellable_cancel")
+	c.gCancellableNew = g.get("g_cancellable_new")
+	c.gErrorFree = g.get("g_error_free")
+	c.gErrorNewLiteral = g.get("g_error_new_literal")
+	c.gDateTimeToUnix = g.get("g_date_time_to_unix")
 	c.gFree = g.get("g_free")
 	c.gIdleAddFull = g.get("g_idle_add_full")
+	c.gInputStreamReadAll = g.get("g_input_stream_read_all")
+	c.gListFreeFull = g.get("g_list_free_full")
+	c.gObjectRef = g.get("g_object_ref")
+	c.gObjectUnref = g.get("g_object_unref")
+	c.gQuarkFromString = g.get("g_quark_from_string")
+	c.gQuarkToString = g.get("g_quark_to_string")
 	c.gSignalConnectData = g.get("g_signal_connect_data")
-	c.gtkContainerAdd = g.get("gtk_container_add")
+	c.gSignalHandlerDisconnect = g.get("g_signal_handler_disconnect")
+	c.gUnixInputStreamNew = g.get("g_unix_input_stream_new")
+	c.gdkPixbufLoaderNew = g.get("gdk_pixbuf_loader_new")
+	c.gdkPixbufLoaderWrite = g.get("gdk_pixbuf_loader_write")
+	c.gdkPixbufLoaderClose = g.get("gdk_pixbuf_loader_close")
+	c.gdkPixbufLoaderGetPixbuf = g.get("gdk_pixbuf_loader_get_pixbuf")
 	c.gtkInitCheck = g.get("gtk_init_check")
-	c.gtkMain = g.get("gtk_main")
-	c.gtkMainQuit = g.get("gtk_main_quit")
 	c.gtkWidgetGrabFocus = g.get("gtk_widget_grab_focus")
 	c.gtkWidgetSetSizeRequest = g.get("gtk_widget_set_size_request")
-	c.gtkWidgetShowAll = g.get("gtk_widget_show_all")
 	c.gtkWindowNew = g.get("gtk_window_new")
-	c.gtkWindowResize = g.get("gtk_window_resize")
-	c.gtkWindowSetGeometryHints = g.get("gtk_window_set_geometry_hints")
 	c.gtkWindowSetResizable = g.get("gtk_window_set_resizable")
 	c.gtkWindowSetTitle = g.get("gtk_window_set_title")
+	c.gtkWindowSetDefaultSize = g.get("gtk_window_set_default_size")
+	c.gtkWindowMaximize = g.get("gtk_window_maximize")
+	c.gtkWindowUnmaximize = g.get("gtk_window_unmaximize")
+	c.gtkWindowFullscreen = g.get("gtk_window_fullscreen")
+	c.gtkWindowUnfullscreen = g.get("gtk_window_unfullscreen")
+	c.gtkWindowSetDecorated = g.get("gtk_window_set_decorated")
+	c.gtkWindowIsActive = g.get("gtk_window_is_active")
+	c.gtkWindowIsMaximized = g.get("gtk_window_is_maximized")
+	c.gtkWindowIsFullscreen = g.getOptional("gtk_window_is_fullscreen")
 
 	// WebKit
+	c.jsCValueToJSON = g.getOptional("jsc_value_to_json")
 	c.jsCValueToString = g.get("jsc_value_to_string")
 	c.webKitGetMajorVersion = g.get("webkit_get_major_version")
 	c.webKitGetMinorVersion = g.get("webkit_get_minor_version")
 	c.webKitGetMicroVersion = g.get("webkit_get_micro_version")
 	c.webKitWebViewNew = g.get("webkit_web_view_new")
+	c.webKitCookieManagerSetPersistentStorage = g.get("webkit_cookie_manager_set_persistent_storage")
+	c.webKitCookieManagerSetAcceptPolicy = g.get("webkit_cookie_manager_set_accept_policy")
+	c.webKitCookieManagerGetCookies = g.get("webkit_cookie_manager_get_cookies")
+	c.webKitCookieManagerGetCookiesFinish = g.get("webkit_cookie_manager_get_cookies_finish")
+	c.webKitCookieManagerAddCookie = g.get("webkit_cookie_manager_add_cookie")
+	c.webKitCookieManagerAddCookieFinish = g.get("webkit_cookie_manager_add_cookie_finish")
+	c.webKitCookieManagerDeleteCookie = g.get("webkit_cookie_manager_delete_cookie")
+	c.webKitCookieManagerDeleteCookieFinish = g.get("webkit_cookie_manager_delete_cookie_finish")
+	c.webKitWebsiteDataManagerClear = g.get("webkit_website_data_manager_clear")
+	c.webKitWebsiteDataManagerClearFinish = g.get("webkit_website_data_manager_clear_finish")
 	c.webKitWebViewGetUserContentManager = g.get("webkit_web_view_get_user_content_manager")
 	c.webKitWebViewGetSettings = g.get("webkit_web_view_get_settings")
 	c.webKitWebViewLoadURI = g.get("webkit_web_view_load_uri")
 	c.webKitWebViewLoadHTML = g.get("webkit_web_view_load_html")
-	c.webKitWebViewRunJavascript = g.get("webkit_web_view_run_javascript")
-	c.webKitJavascriptResultGetJsValue = g.get("webkit_javascript_result_get_js_value")
+	c.webKitWebViewLoadAlternateHTML = g.get("webkit_web_view_load_alternate_html")
+	c.webKitWebViewGetURI = g.get("webkit_web_view_get_uri")
+	c.webKitWebViewGetMainResource = g.get("webkit_web_view_get_main_resource")
+	c.webKitWebResourceGetResponse = g.get("webkit_web_resource_get_response")
+	c.webKitURIResponseGetStatusCode = g.get("webkit_uri_response_get_status_code")
+	c.webKitURIResponseGetMIMEType = g.get("webkit_uri_response_get_mime_type")
+	c.webKitURIRequestGetURI = g.get("webkit_uri_request_get_uri")
+	c.webKitPolicyDecisionUse = g.get("webkit_policy_decision_use")
+	c.webKitPolicyDecisionIgnore = g.get("webkit_policy_decision_ignore")
+	c.webKitNavigationPolicyDecisionGetNavigationAction = g.get("webkit_navigation_policy_decision_get_navigation_action")
+	c.webKitNavigationActionGetRequest = g.get("webkit_navigation_action_get_request")
+	c.webKitNavigationActionIsUserGesture = g.get("webkit_navigation_action_is_user_gesture")
+	c.webKitResponsePolicyDecisionGetRequest = g.get("webkit_response_policy_decision_get_request")
+	c.webKitResponsePolicyDecisionGetResponse = g.get("webkit_response_policy_decision_get_response")
+	c.webKitResponsePolicyDecisionIsMainFrameMainResource = g.getOptional("webkit_response_policy_decision_is_main_frame_main_resource")
 	c.webKitUserContentManagerAddScript = g.get("webkit_user_content_manager_add_script")
+	c.webKitUserContentManagerRemoveAllScripts = g.get("webkit_user_content_manager_remove_all_scripts")
 	c.webKitUserContentManagerRegisterScriptMessageHandler = g.get("webkit_user_content_manager_register_script_message_handler")
+	c.webKitUserContentManagerUnregisterScriptMessageHandler = g.get("webkit_user_content_manager_unregister_script_message_handler")
 	c.webKitUserScriptNew = g.get("webkit_user_script_new")
+	c.webKitUserScriptUnref = g.get("webkit_user_script_unref")
 	c.webKitSettingsSetEnableDeveloperExtras = g.get("webkit_settings_set_enable_developer_extras")
 	c.webKitSettingsSetEnableWriteConsoleMessagesToStdout = g.get("webkit_settings_set_enable_write_console_messages_to_stdout")
 	c.webKitSettingsSetJavascriptCanAccessClipboard = g.get("webkit_settings_set_javascript_can_access_clipboard")
+	c.webKitWebViewGetContext = g.get("webkit_web_view_get_context")
+	c.webKitWebContextRegisterURIScheme = g.get("webkit_web_context_register_uri_scheme")
+	c.webKitWebContextGetSecurityManager = g.get("webkit_web_context_get_security_manager")
+	c.webKitSecurityManagerRegisterURISchemeAsSecure = g.get("webkit_security_manager_register_uri_scheme_as_secure")
+	c.webKitSecurityManagerRegisterURISchemeAsCorsEnabled = g.get("webkit_security_manager_register_uri_scheme_as_cors_enabled")
+	c.webKitURISchemeRequestGetURI = g.get("webkit_uri_scheme_request_get_uri")
+	c.webKitURISchemeRequestGetWebView = g.get("webkit_uri_scheme_request_get_web_view")
+	c.webKitURISchemeRequestGetHTTPMethod = g.getOptional("webkit_uri_scheme_request_get_http_method")
+	c.webKitURISchemeRequestGetHTTPHeaders = g.getOptional("webkit_uri_scheme_request_get_http_headers")
+	c.webKitURISchemeRequestGetHTTPBody = g.getOptional("webkit_uri_scheme_request_get_http_body")
+	c.webKitURISchemeRequestFinish = g.get("webkit_uri_scheme_request_finish")
+	c.webKitURISchemeRequestFinishWithResponse = g.getOptional("webkit_uri_scheme_request_finish_with_response")
+	c.webKitURISchemeRequestFinishError = g.get("webkit_uri_scheme_request_finish_error")
+	c.webKitURISchemeResponseNew = g.getOptional("webkit_uri_scheme_response_new")
+	c.webKitURISchemeResponseSetStatus = g.getOptional("webkit_uri_scheme_response_set_status")
+	c.webKitURISchemeResponseSetContentType = g.getOptional("webkit_uri_scheme_response_set_content_type")
+	c.webKitURISchemeResponseSetHTTPHeaders = g.getOptional("webkit_uri_scheme_response_set_http_headers")
 
-	if g.err != nil {
-		return fmt.Errorf("failed to load functions: %w", g.err)
-	}
-
-	return nil
+	// Soup
+	c.soupMessageHeadersNew = g.get("soup_message_headers_new")
+	c.soupMessageHeadersAppend = g.get("soup_message_headers_append")
+	c.soupMessageHeadersForeach = g.get("soup_message_headers_foreach")
+	c.soupCookieNew = g.get("soup_cookie_new")
+	c.soupCookieFree = g.get("soup_cookie_free")
+	c.soupCookieGetName = g.get("soup_cookie_get_name")
+	c.soupCookieGetValue = g.get("soup_cookie_get_value")
+	c.soupCookieGetDomain = g.get("soup_cookie_get_domain")
+	c.soupCookieGetPath = g.get("soup_cookie_get_path")
+	c.soupCookieGetExpires = g.get("soup_cookie_get_expires")
+	c.soupCookieGetSecure = g.get("soup_cookie_get_secure")
+	c.soupCookieGetHTTPOnly = g.get("soup_cookie_get_http_only")
+	c.soupCookieGetSameSite = g.getOptional("soup_cookie_get_same_site_policy")
+	c.soupCookieSetSecure = g.get("soup_cookie_set_secure")
+	c.soupCookieSetHTTPOnly = g.get("soup_cookie_set_http_only")
+	c.soupCookieSetSameSite = g.getOptional("soup_cookie_set_same_site_policy")
+	// libsoup 2 only
+	c.soupDateToTimeT = g.getOptional("soup_date_to_time_t")
 }
 
 func boolToInt(b bool) int {
@@ -318,6 +1262,30 @@ func cStr(str string) (cstr *byte, free func()) {
 	}
 }
 
+// cStrArray returns the NULL-terminated char** counterpart of strs, or NULL
+// if strs is empty.
+//
+// The returned free function must be called once you are done using the array
+// in order to free the memory.
+func cStrArray(strs []string) (arr uintptr, free func()) {
+	if len(strs) == 0 {
+		return NULLPTR, func() {}
+	}
+
+	ptrs := make([]*byte, len(strs)+1)
+	frees := make([]func(), len(strs))
+	for i, str := range strs {
+		ptrs[i], frees[i] = cStr(str)
+	}
+	return uintptr(unsafe.Pointer(&ptrs[0])), func() {
+		runtime.KeepAlive(ptrs)
+		for _, free := range frees {
+			free()
+		}
+		ptrs = nil
+	}
+}
+
 // goStr copies a char* to a Go string.
 func goStr(c uintptr) string {
 	// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
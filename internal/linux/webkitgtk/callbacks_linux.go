@@ -0,0 +1,269 @@
+//go:build linux
+
+package webkitgtk
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/ebitengine/purego"
+)
+
+// purego can only create a limited number of callbacks and never frees them,
+// so native code is only ever handed a fixed set of trampolines. Each
+// trampoline receives a handle as its user data, which it uses to look up the
+// Go function to call in the registry. Handles are released when GLib
+// destroys the user data, or right after the call for one-shot callbacks.
+
+// maxSignalArgs is the number of arguments, including the instance, a signal
+// handler may take so that all of them are passed in registers.
+const maxSignalArgs = 5
+
+type callbackRegistry struct {
+	mutex   sync.Mutex
+	next    uintptr
+	entries map[uintptr]interface{}
+}
+
+var callbacks = &callbackRegistry{
+	entries: make(map[uintptr]interface{}),
+}
+
+// register stores entry and returns the handle to pass as user data.
+func (r *callbackRegistry) register(entry interface{}) uintptr {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.next++
+	r.entries[r.next] = entry
+	return r.next
+}
+
+func (r *callbackRegistry) get(handle uintptr) interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.entries[handle]
+}
+
+// release removes the entry for handle and returns it.
+func (r *callbackRegistry) release(handle uintptr) interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry := r.entries[handle]
+	delete(r.entries, handle)
+	return entry
+}
+
+type sourceFuncEntry struct {
+	function GSourceFunc
+	data     uintptr
+	notify   GDestroyNotify
+}
+
+type signalEntry struct {
+	handler reflect.Value
+	data    uintptr
+	notify  GClosureNotify
+}
+
+type asyncReadyEntry struct {
+	callback GAsyncReadyCallback
+	data     uintptr
+}
+
+type uriSchemeEntry struct {
+	callback WebKitURISchemeRequestCallback
+	data     uintptr
+	notify   GDestroyNotify
+}
+
+type headersForeachEntry struct {
+	f func(name string, value string)
+}
+
+var trampolines struct {
+	once sync.Once
+
+	// gboolean (*GSourceFunc)(gpointer user_data)
+	sourceFunc uintptr
+	// void (*GDestroyNotify)(gpointer data)
+	destroyNotify uintptr
+	// void (*GClosureNotify)(gpointer data, GClosure *closure)
+	closureNotify uintptr
+	// Signal handlers are connected with G_CONNECT_SWAPPED so the user data
+	// always comes first: (gpointer user_data, args..., gpointer instance).
+	signal uintptr
+	// void (*GAsyncReadyCallback)(GObject *source_object, GAsyncResult *res, gpointer user_data)
+	asyncReady uintptr
+	// void (*WebKitURISchemeRequestCallback)(WebKitURISchemeRequest *request, gpointer user_data)
+	uriScheme uintptr
+	// void (*SoupMessageHeadersForeachFunc)(const char *name, const char *value, gpointer user_data)
+	headersForeach uintptr
+}
+
+func initTrampolines() {
+	trampolines.once.Do(func() {
+		trampolines.sourceFunc = purego.NewCallback(sourceFuncTrampoline)
+		trampolines.destroyNotify = purego.NewCallback(destroyNotifyTrampoline)
+		trampolines.closureNotify = purego.NewCallback(closureNotifyTrampoline)
+		trampolines.signal = purego.NewCallback(signalTrampoline)
+		trampolines.asyncReady = purego.NewCallback(asyncReadyTrampoline)
+		trampolines.uriScheme = purego.NewCallback(uriSchemeTrampoline)
+		trampolines.headersForeach = purego.NewCallback(headersForeachTrampoline)
+	})
+}
+
+func sourceFuncTrampoline(handle uintptr) uintptr {
+	entry, ok := callbacks.get(handle).(*sourceFuncEntry)
+	if !ok {
+		return uintptr(boolToInt(G_SOURCE_REMOVE))
+	}
+
+	return uintptr(boolToInt(entry.function(entry.data)))
+}
+
+func destroyNotifyTrampoline(handle uintptr) {
+	switch entry := callbacks.release(handle).(type) {
+	case *sourceFuncEntry:
+		if entry.notify != nil {
+			entry.notify(entry.data)
+		}
+	case *uriSchemeEntry:
+		if entry.notify != nil {
+			entry.notify(entry.data)
+		}
+	}
+}
+
+func closureNotifyTrampoline(handle uintptr, closure uintptr) {
+	entry, ok := callbacks.release(handle).(*signalEntry)
+	if ok && entry.notify != nil {
+		entry.notify(entry.data, closure)
+	}
+}
+
+func signalTrampoline(handle uintptr, a1, a2, a3, a4, a5 uintptr) uintptr {
+	entry, ok := callbacks.get(handle).(*signalEntry)
+	if !ok {
+		return 0
+	}
+
+	// Restore the (instance, args..., user_data) order of the handler.
+	raw := [maxSignalArgs]uintptr{a1, a2, a3, a4, a5}
+	t := entry.handler.Type()
+	numArgs := t.NumIn() - 2
+	values := make([]uintptr, 0, t.NumIn())
+	values = append(values, raw[numArgs])
+	values = append(values, raw[:numArgs]...)
+	values = append(values, entry.data)
+
+	args := make([]reflect.Value, len(values))
+	for i, v := range values {
+		args[i] = convertArg(v, t.In(i))
+	}
+
+	res := entry.handler.Call(args)
+	if len(res) == 0 {
+		return 0
+	}
+	switch res[0].Kind() {
+	case reflect.Bool:
+		return uintptr(boolToInt(res[0].Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uintptr(res[0].Int())
+	default:
+		return uintptr(res[0].Uint())
+	}
+}
+
+func asyncReadyTrampoline(sourceObject uintptr, res uintptr, handle uintptr) {
+	entry, ok := callbacks.release(handle).(*asyncReadyEntry)
+	if ok {
+		entry.callback(GObject(sourceObject), GAsyncResult(res), entry.data)
+	}
+}
+
+func uriSchemeTrampoline(request uintptr, handle uintptr) {
+	entry, ok := callbacks.get(handle).(*uriSchemeEntry)
+	if ok {
+		entry.callback(WebKitURISchemeRequest(request), entry.data)
+	}
+}
+
+func headersForeachTrampoline(name uintptr, value uintptr, handle uintptr) {
+	entry, ok := callbacks.get(handle).(*headersForeachEntry)
+	if ok {
+		entry.f(goStr(name), goStr(value))
+	}
+}
+
+// convertArg converts a raw native argument to the type a handler expects.
+func convertArg(v uintptr, t reflect.Type) reflect.Value {
+	switch t.Kind() {
+	case reflect.Bool:
+		// gboolean is an int
+		return reflect.ValueOf(uint32(v) != 0).Convert(t)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(int64(v)).Convert(t)
+	default:
+		return reflect.ValueOf(v).Convert(t)
+	}
+}
+
+// newSignalEntry validates that handler follows the
+// func(instance, args..., userData) convention of GCallback.
+func newSignalEntry(handler GCallback, data uintptr, notify GClosureNotify) (*signalEntry, error) {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func {
+		return nil, fmt.Errorf("signal handler must be a function, got %T", handler)
+	}
+
+	t := v.Type()
+	if t.NumIn() < 2 || t.NumIn() > maxSignalArgs+1 {
+		return nil, fmt.Errorf("signal handler must take between 2 and %d arguments, got %d", maxSignalArgs+1, t.NumIn())
+	}
+	if t.NumOut() > 1 {
+		return nil, fmt.Errorf("signal handler may return at most one value")
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		if !isScalar(t.In(i)) {
+			return nil, fmt.Errorf("unsupported signal handler argument type: %s", t.In(i))
+		}
+	}
+	if t.NumOut() == 1 && !isScalar(t.Out(0)) {
+		return nil, fmt.Errorf("unsupported signal handler return type: %s", t.Out(0))
+	}
+
+	return &signalEntry{
+		handler: v,
+		data:    data,
+		notify:  notify,
+	}, nil
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// registerAsyncReady returns the trampoline and user data to pass to an
+// asynchronous function for callback, or NULL for both if callback is nil.
+func registerAsyncReady(callback GAsyncReadyCallback, userData uintptr) (uintptr, uintptr) {
+	if callback == nil {
+		return NULLPTR, NULLPTR
+	}
+
+	initTrampolines()
+	return trampolines.asyncReady, callbacks.register(&asyncReadyEntry{
+		callback: callback,
+		data:     userData,
+	})
+}
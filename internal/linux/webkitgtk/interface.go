@@ -1,27 +1,51 @@
+//go:build linux
+
 package webkitgtk
 
 type (
 	GAsyncResult uintptr
+	GdkPixbuf    uintptr
 	GCancellable uintptr
+	GInputStream uintptr
 	GObject      uintptr
 	GtkContainer uintptr
 	GtkWidget    uintptr
 	GtkWindow    uintptr
 
+	SoupCookie         uintptr
+	SoupMessageHeaders uintptr
+
 	GAsyncReadyCallback func(sourceObject GObject, res GAsyncResult, userData uintptr)
 	GDestroyNotify      func(data uintptr)
 	GSourceFunc         func(userData uintptr) bool
-	GCallback           interface{}
-	GClosureNotify      func(data uintptr, closure uintptr)
+	// GCallback is a signal handler of the form
+	// func(instance, args..., userData uintptr), where every argument and the
+	// optional return value is an integer, uintptr or bool type.
+	GCallback      interface{}
+	GClosureNotify func(data uintptr, closure uintptr)
+
+	WebKitURISchemeRequestCallback func(request WebKitURISchemeRequest, userData uintptr)
 
 	JSCValue                 uintptr
+	WebKitCookieManager      uintptr
+	WebKitNetworkSession     uintptr
 	JSContextRef             uintptr
 	JSValueRef               uintptr
 	WebKitJavascriptResult   uintptr
+	WebKitNavigationAction   uintptr
+	WebKitPolicyDecision     uintptr
+	WebKitSecurityManager    uintptr
 	WebKitSettings           uintptr
+	WebKitURISchemeRequest   uintptr
+	WebKitURISchemeResponse  uintptr
 	WebKitUserContentManager uintptr
+	WebKitURIRequest         uintptr
+	WebKitURIResponse        uintptr
 	WebKitUserScript         uintptr
+	WebKitWebContext         uintptr
+	WebKitWebResource        uintptr
 	WebKitWebView            uintptr
+	WebKitWebsiteDataManager uintptr
 )
 
 const (
@@ -36,6 +60,17 @@ const (
 	NULLPTR uintptr = 0
 )
 
+// GError is a copy of a GLib GError, with the name of its domain quark.
+type GError struct {
+	Domain  string
+	Code    int
+	Message string
+}
+
+func (e *GError) Error() string {
+	return e.Message
+}
+
 type GdkGravity uint
 
 const (
@@ -65,6 +100,34 @@ type GdkGeometry struct {
 	WinGravity GdkGravity
 }
 
+// GdkEventConfigure holds the fields of a GTK 3 GdkEventConfigure.
+type GdkEventConfigure struct {
+	X      int32
+	Y      int32
+	Width  int32
+	Height int32
+}
+
+type GdkWindowState uint32
+
+const (
+	GDK_WINDOW_STATE_WITHDRAWN GdkWindowState = 1 << iota
+	GDK_WINDOW_STATE_ICONIFIED
+	GDK_WINDOW_STATE_MAXIMIZED
+	GDK_WINDOW_STATE_STICKY
+	GDK_WINDOW_STATE_FULLSCREEN
+	GDK_WINDOW_STATE_ABOVE
+	GDK_WINDOW_STATE_BELOW
+	GDK_WINDOW_STATE_FOCUSED
+	GDK_WINDOW_STATE_TILED
+)
+
+// GdkEventWindowState holds the fields of a GTK 3 GdkEventWindowState.
+type GdkEventWindowState struct {
+	ChangedMask    GdkWindowState
+	NewWindowState GdkWindowState
+}
+
 type GdkWindowHints uint
 
 const (
@@ -86,6 +149,16 @@ const (
 	GTK_WINDOW_POPUP
 )
 
+type GtkWindowPosition uint
+
+const (
+	GTK_WIN_POS_NONE GtkWindowPosition = iota
+	GTK_WIN_POS_CENTER
+	GTK_WIN_POS_MOUSE
+	GTK_WIN_POS_CENTER_ALWAYS
+	GTK_WIN_POS_CENTER_ON_PARENT
+)
+
 type GConnectFlags uint
 
 const (
@@ -94,6 +167,14 @@ const (
 	G_CONNECT_SWAPPED
 )
 
+type SoupMessageHeadersType uint
+
+const (
+	SOUP_MESSAGE_HEADERS_REQUEST SoupMessageHeadersType = iota
+	SOUP_MESSAGE_HEADERS_RESPONSE
+	SOUP_MESSAGE_HEADERS_MULTIPART
+)
+
 type WebKitHardwareAccelerationPolicy uint
 
 const (
@@ -102,6 +183,41 @@ const (
 	WEBKIT_HARDWARE_ACCELERATION_POLICY_NEVER
 )
 
+// Domains and codes of the errors reported by load-failed.
+const (
+	WEBKIT_NETWORK_ERROR = "WebKitNetworkError"
+	WEBKIT_POLICY_ERROR  = "WebKitPolicyError"
+
+	WEBKIT_NETWORK_ERROR_CANCELLED                              = 302
+	WEBKIT_POLICY_ERROR_FRAME_LOAD_INTERRUPTED_BY_POLICY_CHANGE = 102
+)
+
+// Domains and codes of the errors reported by WebKitWebViewRunJavascriptFinish.
+const (
+	G_IO_ERROR              = "g-io-error-quark"
+	WEBKIT_JAVASCRIPT_ERROR = "WebKitJavascriptError"
+
+	G_IO_ERROR_CANCELLED                  = 19
+	WEBKIT_JAVASCRIPT_ERROR_SCRIPT_FAILED = 699
+)
+
+type WebKitLoadEvent uint
+
+const (
+	WEBKIT_LOAD_STARTED WebKitLoadEvent = iota
+	WEBKIT_LOAD_REDIRECTED
+	WEBKIT_LOAD_COMMITTED
+	WEBKIT_LOAD_FINISHED
+)
+
+type WebKitPolicyDecisionType uint
+
+const (
+	WEBKIT_POLICY_DECISION_TYPE_NAVIGATION_ACTION WebKitPolicyDecisionType = iota
+	WEBKIT_POLICY_DECISION_TYPE_NEW_WINDOW_ACTION
+	WEBKIT_POLICY_DECISION_TYPE_RESPONSE
+)
+
 type WebKitUserContentInjectedFrames uint
 
 const (
@@ -109,6 +225,59 @@ const (
 	WEBKIT_USER_CONTENT_INJECT_TOP_FRAME
 )
 
+type WebKitCookiePersistentStorage uint
+
+const (
+	WEBKIT_COOKIE_PERSISTENT_STORAGE_TEXT WebKitCookiePersistentStorage = iota
+	WEBKIT_COOKIE_PERSISTENT_STORAGE_SQLITE
+)
+
+type WebKitCookieAcceptPolicy uint
+
+const (
+	WEBKIT_COOKIE_POLICY_ACCEPT_ALWAYS WebKitCookieAcceptPolicy = iota
+	WEBKIT_COOKIE_POLICY_ACCEPT_NEVER
+	WEBKIT_COOKIE_POLICY_ACCEPT_NO_THIRD_PARTY
+)
+
+type WebKitWebsiteDataTypes uint
+
+const (
+	WEBKIT_WEBSITE_DATA_MEMORY_CACHE WebKitWebsiteDataTypes = 1 << iota
+	WEBKIT_WEBSITE_DATA_DISK_CACHE
+	WEBKIT_WEBSITE_DATA_OFFLINE_APPLICATION_CACHE
+	WEBKIT_WEBSITE_DATA_SESSION_STORAGE
+	WEBKIT_WEBSITE_DATA_LOCAL_STORAGE
+	WEBKIT_WEBSITE_DATA_WEBSQL_DATABASES
+	WEBKIT_WEBSITE_DATA_INDEXEDDB_DATABASES
+	WEBKIT_WEBSITE_DATA_PLUGIN_DATA
+	WEBKIT_WEBSITE_DATA_COOKIES
+)
+
+type SoupSameSitePolicy uint
+
+const (
+	SOUP_SAME_SITE_POLICY_NONE SoupSameSitePolicy = iota
+	SOUP_SAME_SITE_POLICY_LAX
+	SOUP_SAME_SITE_POLICY_STRICT
+)
+
+// SoupCookieData holds the fields of a SoupCookie, which differ in type
+// between libsoup 2 and 3.
+type SoupCookieData struct {
+	Name   string
+	Value  string
+	Domain string
+	Path   string
+	// Expires is the expiry in seconds since the epoch, or 0 for a session
+	// cookie.
+	Expires  int64
+	Secure   bool
+	HTTPOnly bool
+	// SameSite is ignored by libsoup versions older than 2.70.
+	SameSite SoupSameSitePolicy
+}
+
 type WebKitUserScriptInjectionTime uint
 
 const (
@@ -119,11 +288,38 @@ const (
 type Context interface {
 	LoadFunctions() error
 
+	// Library returns the library the functions are loaded from. Its ABI
+	// determines which functions and signatures are available.
+	Library() *Library
+
+	// CopyString and CopyError copy a char* or GError* owned by the caller,
+	// such as the arguments of a signal handler.
+	CopyString(str uintptr) string
+	CopyError(gerr uintptr) *GError
+
+	// CopyEventConfigure and CopyEventWindowState copy the GdkEvent* passed
+	// to the configure-event and window-state-event handlers of GTK 3.
+	CopyEventConfigure(event uintptr) GdkEventConfigure
+	CopyEventWindowState(event uintptr) GdkEventWindowState
+
 	// GLib
 	GFree(mem uintptr)
 	GIdleAddFull(priority int, function GSourceFunc, data uintptr, notify GDestroyNotify)
 	GSignalConnectData(instance GtkWidget, detailedSignal string, cHandler GCallback, data uintptr, destroyData GClosureNotify, connectFlags GConnectFlags) uint32
+	GSignalHandlerDisconnect(instance GtkWidget, handlerID uint32)
+	GCancellableNew() GCancellable
+	GCancellableCancel(cancellable GCancellable)
+	GObjectRef(object GObject)
+	GObjectUnref(object GObject)
+	GInputStreamReadAll(stream GInputStream, buffer []byte) (int, error)
+	GUnixInputStreamNew(fd int, closeFd bool) GInputStream
+
+	// GdkPixbuf
+	GdkPixbufNewFromBytes(data []byte) (GdkPixbuf, error)
+
+	// GTK
 	GtkContainerAdd(container GtkContainer, widget GtkWidget)
+	GtkContainerRemove(container GtkContainer, widget GtkWidget)
 	GtkInitCheck() bool
 	GtkMain()
 	GtkMainQuit()
@@ -131,27 +327,112 @@ type Context interface {
 	GtkWidgetSetSizeRequest(widget GtkWidget, width, height int)
 	GtkWidgetShowAll(widget GtkWidget)
 	GtkWindowNew(windowType GtkWindowType) GtkWidget
+	GtkWindowDestroy(window GtkWindow)
 	GtkWindowResize(window GtkWindow, width, height int)
+	GtkWindowSetDefaultSize(window GtkWindow, width, height int)
+	GtkWindowSetPosition(window GtkWindow, position GtkWindowPosition)
+	GtkWindowSetIcon(window GtkWindow, icon GdkPixbuf)
+	GtkWindowGetSize(window GtkWindow) (width, height int)
+	GtkWindowMove(window GtkWindow, x, y int)
+	GtkWindowGetPosition(window GtkWindow) (x, y int)
+	GtkWindowMaximize(window GtkWindow)
+	GtkWindowUnmaximize(window GtkWindow)
+	GtkWindowIconify(window GtkWindow)
+	GtkWindowFullscreen(window GtkWindow)
+	GtkWindowUnfullscreen(window GtkWindow)
+	GtkWindowSetKeepAbove(window GtkWindow, setting bool)
+	GtkWindowSetDecorated(window GtkWindow, setting bool)
+	GtkWindowIsActive(window GtkWindow) bool
+	GtkWindowIsMaximized(window GtkWindow) bool
+	GtkWindowIsFullscreen(window GtkWindow) bool
 	GtkWindowSetGeometryHints(window GtkWindow, geometryWidget GtkWidget, geometry GdkGeometry, geomMask GdkWindowHints)
 	GtkWindowSetResizable(window GtkWindow, resizable bool)
 	GtkWindowSetTitle(window GtkWindow, title string)
 
 	// WebKit
 	JsCValueToString(value JSCValue) string
+	JsCValueToJSON(value JSCValue, indent uint) string
 	WebKitGetMajorVersion() uint32
 	WebKitGetMinorVersion() uint32
 	WebKitGetMicroVersion() uint32
 	WebKitWebViewNew() GtkWidget
+
+	// WebKitNetworkSession holds the website data of the web views created
+	// with it. WebKit2GTK has no network sessions, so there they are
+	// implemented as a WebKitWebContext with its own website data manager.
+	// Sessions returned by the New functions must be released with
+	// GObjectUnref.
+	WebKitNetworkSessionGetDefault() WebKitNetworkSession
+	WebKitNetworkSessionNew(dataDirectory string, cacheDirectory string) WebKitNetworkSession
+	WebKitNetworkSessionNewEphemeral() WebKitNetworkSession
+	WebKitNetworkSessionGetCookieManager(session WebKitNetworkSession) WebKitCookieManager
+	WebKitWebViewNewWithNetworkSession(session WebKitNetworkSession) GtkWidget
+	WebKitNetworkSessionGetWebsiteDataManager(session WebKitNetworkSession) WebKitWebsiteDataManager
+	WebKitWebsiteDataManagerClear(manager WebKitWebsiteDataManager, types WebKitWebsiteDataTypes, timespan int64, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr)
+	WebKitWebsiteDataManagerClearFinish(manager WebKitWebsiteDataManager, result GAsyncResult) error
+	WebKitCookieManagerSetPersistentStorage(manager WebKitCookieManager, filename string, storage WebKitCookiePersistentStorage)
+	WebKitCookieManagerSetAcceptPolicy(manager WebKitCookieManager, policy WebKitCookieAcceptPolicy)
+	WebKitCookieManagerGetCookies(manager WebKitCookieManager, uri string, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr)
+	WebKitCookieManagerGetCookiesFinish(manager WebKitCookieManager, result GAsyncResult) ([]SoupCookieData, error)
+	WebKitCookieManagerAddCookie(manager WebKitCookieManager, cookie SoupCookie, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr)
+	WebKitCookieManagerAddCookieFinish(manager WebKitCookieManager, result GAsyncResult) error
+	WebKitCookieManagerDeleteCookie(manager WebKitCookieManager, cookie SoupCookie, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr)
+	WebKitCookieManagerDeleteCookieFinish(manager WebKitCookieManager, result GAsyncResult) error
+
 	WebKitWebViewGetUserContentManager(webview WebKitWebView) WebKitUserContentManager
 	WebKitWebViewGetSettings(webview WebKitWebView) WebKitSettings
 	WebKitWebViewLoadURI(webview WebKitWebView, uri string)
 	WebKitWebViewLoadHTML(webview WebKitWebView, content string, baseUri string)
+	WebKitWebViewLoadAlternateHTML(webview WebKitWebView, content string, contentUri string, baseUri string)
+	WebKitWebViewGetURI(webview WebKitWebView) string
+	WebKitWebViewGetMainResource(webview WebKitWebView) WebKitWebResource
+	WebKitWebResourceGetResponse(resource WebKitWebResource) WebKitURIResponse
+	WebKitURIResponseGetStatusCode(response WebKitURIResponse) uint
+	WebKitURIResponseGetMIMEType(response WebKitURIResponse) string
+	WebKitURIRequestGetURI(request WebKitURIRequest) string
+	WebKitPolicyDecisionUse(decision WebKitPolicyDecision)
+	WebKitPolicyDecisionIgnore(decision WebKitPolicyDecision)
+	WebKitNavigationPolicyDecisionGetNavigationAction(decision WebKitPolicyDecision) WebKitNavigationAction
+	WebKitNavigationActionGetRequest(action WebKitNavigationAction) WebKitURIRequest
+	WebKitNavigationActionIsUserGesture(action WebKitNavigationAction) bool
+	WebKitResponsePolicyDecisionGetRequest(decision WebKitPolicyDecision) WebKitURIRequest
+	WebKitResponsePolicyDecisionGetResponse(decision WebKitPolicyDecision) WebKitURIResponse
+	WebKitResponsePolicyDecisionIsMainFrameMainResource(decision WebKitPolicyDecision) bool
 	WebKitWebViewRunJavascript(webview WebKitWebView, script string, cancellable GCancellable, callback GAsyncReadyCallback, userData uintptr)
+	WebKitWebViewRunJavascriptFinish(webview WebKitWebView, result GAsyncResult) (WebKitJavascriptResult, error)
 	WebKitJavascriptResultGetJsValue(jsResult WebKitJavascriptResult) JSCValue
+	WebKitJavascriptResultUnref(jsResult WebKitJavascriptResult)
 	WebKitUserContentManagerAddScript(manager WebKitUserContentManager, script WebKitUserScript)
+	WebKitUserContentManagerRemoveAllScripts(manager WebKitUserContentManager)
 	WebKitUserContentManagerRegisterScriptMessageHandler(manager WebKitUserContentManager, name string)
-	WebKitUserScriptNew(source string, injectedFrames WebKitUserContentInjectedFrames, injectionTime WebKitUserScriptInjectionTime, whitelist string, blacklist string) WebKitUserScript
+	WebKitUserContentManagerUnregisterScriptMessageHandler(manager WebKitUserContentManager, name string)
+	WebKitUserScriptNew(source string, injectedFrames WebKitUserContentInjectedFrames, injectionTime WebKitUserScriptInjectionTime, allowList []string, blockList []string) WebKitUserScript
+	WebKitUserScriptUnref(script WebKitUserScript)
 	WebKitSettingsSetEnableDeveloperExtras(settings WebKitSettings, enabled bool)
 	WebKitSettingsSetEnableWriteConsoleMessagesToStdout(settings WebKitSettings, enabled bool)
 	WebKitSettingsSetJavascriptCanAccessClipboard(settings WebKitSettings, enabled bool)
+	WebKitWebViewGetContext(webview WebKitWebView) WebKitWebContext
+	WebKitWebContextRegisterURIScheme(context WebKitWebContext, scheme string, callback WebKitURISchemeRequestCallback, userData uintptr, notify GDestroyNotify)
+	WebKitWebContextGetSecurityManager(context WebKitWebContext) WebKitSecurityManager
+	WebKitSecurityManagerRegisterURISchemeAsSecure(manager WebKitSecurityManager, scheme string)
+	WebKitSecurityManagerRegisterURISchemeAsCorsEnabled(manager WebKitSecurityManager, scheme string)
+	WebKitURISchemeRequestGetURI(request WebKitURISchemeRequest) string
+	WebKitURISchemeRequestGetWebView(request WebKitURISchemeRequest) WebKitWebView
+	WebKitURISchemeRequestGetHTTPMethod(request WebKitURISchemeRequest) string
+	WebKitURISchemeRequestGetHTTPHeaders(request WebKitURISchemeRequest) SoupMessageHeaders
+	WebKitURISchemeRequestGetHTTPBody(request WebKitURISchemeRequest) GInputStream
+	WebKitURISchemeRequestFinish(request WebKitURISchemeRequest, stream GInputStream, length int64, contentType string)
+	WebKitURISchemeRequestFinishWithResponse(request WebKitURISchemeRequest, response WebKitURISchemeResponse)
+	WebKitURISchemeRequestFinishError(request WebKitURISchemeRequest, message string)
+	WebKitURISchemeResponseNew(stream GInputStream, length int64) WebKitURISchemeResponse
+	WebKitURISchemeResponseSetStatus(response WebKitURISchemeResponse, statusCode int, reasonPhrase string)
+	WebKitURISchemeResponseSetContentType(response WebKitURISchemeResponse, contentType string)
+	WebKitURISchemeResponseSetHTTPHeaders(response WebKitURISchemeResponse, headers SoupMessageHeaders)
+
+	// Soup
+	SoupMessageHeadersNew(headersType SoupMessageHeadersType) SoupMessageHeaders
+	SoupMessageHeadersAppend(headers SoupMessageHeaders, name string, value string)
+	SoupMessageHeadersForeach(headers SoupMessageHeaders, f func(name string, value string))
+	SoupCookieNew(cookie SoupCookieData) SoupCookie
+	SoupCookieFree(cookie SoupCookie)
 }
@@ -1,3 +1,5 @@
+//go:build linux
+
 package webkitgtk
 
 import (
@@ -26,3 +28,14 @@ func (p *procAddressGetter) get(name string) uintptr {
 
 	return proc
 }
+
+// getOptional resolves a function that is only available in some library
+// versions. Missing functions resolve to 0 without failing the getter.
+func (p *procAddressGetter) getOptional(name string) uintptr {
+	proc, err := p.ctx.getProcAddress(name)
+	if err != nil {
+		return 0
+	}
+
+	return proc
+}
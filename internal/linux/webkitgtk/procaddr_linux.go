@@ -3,27 +3,127 @@
 package webkitgtk
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/ebitengine/purego"
 )
 
-var (
-	opengl uintptr
+// ErrLibraryNotFound is returned when no WebKitGTK library could be loaded.
+var ErrLibraryNotFound = errors.New("webkitgtk: library not found")
+
+// LibraryEnv is the environment variable that overrides library discovery
+// with a soname or path.
+const LibraryEnv = "WEBVIEW_WEBKITGTK_LIBRARY"
+
+// ABI identifies the API a WebKitGTK library implements.
+type ABI int
+
+const (
+	// ABI40 is webkit2gtk-4.0, built against GTK 3 and libsoup 2.
+	ABI40 ABI = iota
+	// ABI41 is webkit2gtk-4.1, built against GTK 3 and libsoup 3.
+	ABI41
+	// ABI60 is webkitgtk-6.0, built against GTK 4 and libsoup 3.
+	ABI60
 )
 
-func (c *defaultContext) init() error {
-	lib, err := purego.Dlopen("libwebkit2gtk-4.0.so", purego.RTLD_LAZY|purego.RTLD_GLOBAL)
-	if err != nil {
-		return fmt.Errorf("failed to load libwebkit2gtk: %w", err)
+func (a ABI) String() string {
+	switch a {
+	case ABI40:
+		return "webkit2gtk-4.0"
+	case ABI41:
+		return "webkit2gtk-4.1"
+	case ABI60:
+		return "webkitgtk-6.0"
+	default:
+		return fmt.Sprintf("ABI(%d)", int(a))
+	}
+}
+
+// libraryCandidates lists the sonames probed by OpenLibrary, in order of
+// preference. Versioned sonames come first since the unversioned symlinks
+// are usually only installed with the development packages.
+var libraryCandidates = []string{
+	"libwebkit2gtk-4.1.so.0",
+	"libwebkit2gtk-4.0.so.37",
+	"libwebkitgtk-6.0.so.4",
+	"libwebkit2gtk-4.1.so",
+	"libwebkit2gtk-4.0.so",
+	"libwebkitgtk-6.0.so",
+}
+
+// Library is a loaded WebKitGTK library.
+type Library struct {
+	// Name is the soname or path the library was loaded from.
+	Name string
+	// ABI is the API implemented by the library.
+	ABI ABI
+
+	handle uintptr
+}
+
+// OpenLibrary loads the WebKitGTK library with the given soname or path. If
+// name is empty the LibraryEnv environment variable is used, and if that is
+// unset as well the known sonames are probed in order of preference.
+func OpenLibrary(name string) (*Library, error) {
+	if name == "" {
+		name = os.Getenv(LibraryEnv)
+	}
+
+	candidates := libraryCandidates
+	if name != "" {
+		candidates = []string{name}
+	}
+
+	var errs []string
+	for _, candidate := range candidates {
+		handle, err := purego.Dlopen(candidate, purego.RTLD_LAZY|purego.RTLD_GLOBAL)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		return &Library{
+			Name:   candidate,
+			ABI:    detectABI(handle),
+			handle: handle,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: tried %s: %s", ErrLibraryNotFound, strings.Join(candidates, ", "), strings.Join(errs, "; "))
+}
+
+// detectABI inspects the symbols of a loaded library, so that libraries
+// loaded by path are identified as well.
+func detectABI(handle uintptr) ABI {
+	// GTK 4 has no containers.
+	if _, err := purego.Dlsym(handle, "gtk_container_add"); err != nil {
+		return ABI60
+	}
+
+	if proc, err := purego.Dlsym(handle, "soup_get_major_version"); err == nil {
+		if major, _, _ := purego.SyscallN(proc); major >= 3 {
+			return ABI41
+		}
+	}
+
+	return ABI40
+}
+
+func (c *defaultContext) init(lib *Library) error {
+	if lib.ABI == ABI60 {
+		return fmt.Errorf("%s (%s) requires the GTK 4 context", lib.Name, lib.ABI)
 	}
 
-	opengl = lib
+	c.lib = lib
 	return nil
 }
 
 func (c *defaultContext) getProcAddress(name string) (uintptr, error) {
-	proc, err := purego.Dlsym(opengl, name)
+	proc, err := purego.Dlsym(c.lib.handle, name)
 	if err != nil {
 		return 0, fmt.Errorf("failed to load proc address: %w", err)
 	}
@@ -0,0 +1,1284 @@
+//go:build linux
+
+// Package webkitgtktest provides a fake webkitgtk.Context that records the
+// calls made to it instead of calling into WebKitGTK, so that the glue code
+// on top of it can be tested without a display or native libraries.
+package webkitgtktest
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+// Call is a recorded call. Args holds the arguments in order, except for
+// Go functions, which can't be compared and are left out.
+type Call struct {
+	Name string
+	Args []interface{}
+}
+
+// Recorder implements webkitgtk.Context. Objects created through it are
+// distinct non-zero handles, signal handlers are stored so they can be
+// invoked with Emit, and idle functions run on the fake main loop.
+type Recorder struct {
+	// ABI is reported by Library.
+	ABI webkitgtk.ABI
+	// Major, Minor and Micro are the reported WebKitGTK version.
+	Major, Minor, Micro uint32
+	// NoDisplay makes GtkInitCheck fail.
+	NoDisplay bool
+	// EvalFunc computes the JSON result of scripts run with
+	// WebKitWebViewRunJavascript. If nil, every script evaluates to undefined.
+	// Its errors are returned by WebKitWebViewRunJavascriptFinish, exceptions
+	// are a *webkitgtk.GError of the WEBKIT_JAVASCRIPT_ERROR domain.
+	EvalFunc func(script string) (string, error)
+	// StatusCode is the HTTP status of every main resource.
+	StatusCode uint
+
+	mutex    sync.Mutex
+	calls    []Call
+	next     uintptr
+	handlers []*signalHandler
+	values   map[uintptr]string
+	errors   map[uintptr]*webkitgtk.GError
+	uris     map[uintptr]string
+	sizes    map[uintptr][2]int
+	points   map[uintptr][2]int
+	states   map[uintptr]webkitgtk.GdkWindowState
+	events   map[uintptr]interface{}
+	requests map[uintptr]request
+	results  map[uintptr]evalResult
+	headers  map[uintptr][][2]string
+	cookies  map[uintptr]webkitgtk.SoupCookieData
+	lists    map[uintptr][]webkitgtk.SoupCookieData
+	jar      []webkitgtk.SoupCookieData
+	schemes  map[schemeKey]uriScheme
+	contexts map[uintptr]webkitgtk.WebKitWebContext
+	context  webkitgtk.WebKitWebContext
+	idle     []func()
+	wake     chan struct{}
+	quit     bool
+}
+
+type signalHandler struct {
+	id       uint32
+	instance uintptr
+	signal   string
+	handler  reflect.Value
+	data     uintptr
+}
+
+// request backs the policy decisions created by NewPolicyDecision and the
+// scheme requests created by NewSchemeRequest. The decision, its action,
+// request and response share the same handle.
+type request struct {
+	uri         string
+	userGesture bool
+	mimeType    string
+	subframe    bool
+	webview     webkitgtk.WebKitWebView
+}
+
+type schemeKey struct {
+	context webkitgtk.WebKitWebContext
+	scheme  string
+}
+
+type uriScheme struct {
+	callback webkitgtk.WebKitURISchemeRequestCallback
+	userData uintptr
+	notify   webkitgtk.GDestroyNotify
+}
+
+type evalResult struct {
+	value webkitgtk.WebKitJavascriptResult
+	err   error
+}
+
+var _ webkitgtk.Context = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder reporting WebKitGTK 2.40.0 with the
+// webkit2gtk-4.1 ABI.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		ABI:      webkitgtk.ABI41,
+		Major:    2,
+		Minor:    40,
+		Micro:    0,
+		values:   make(map[uintptr]string),
+		errors:   make(map[uintptr]*webkitgtk.GError),
+		uris:     make(map[uintptr]string),
+		sizes:    make(map[uintptr][2]int),
+		points:   make(map[uintptr][2]int),
+		states:   make(map[uintptr]webkitgtk.GdkWindowState),
+		events:   make(map[uintptr]interface{}),
+		requests: make(map[uintptr]request),
+		results:  make(map[uintptr]evalResult),
+		headers:  make(map[uintptr][][2]string),
+		cookies:  make(map[uintptr]webkitgtk.SoupCookieData),
+		lists:    make(map[uintptr][]webkitgtk.SoupCookieData),
+		schemes:  make(map[schemeKey]uriScheme),
+		contexts: make(map[uintptr]webkitgtk.WebKitWebContext),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Calls returns all recorded calls in order.
+func (r *Recorder) Calls() []Call {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns the recorded calls to the named method in order.
+func (r *Recorder) CallsTo(name string) []Call {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var calls []Call
+	for _, call := range r.calls {
+		if call.Name == name {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Emit calls the handlers connected to the signal of instance with args,
+// which must match the arguments the handlers take between the instance and
+// the user data. It returns the result of the last handler.
+func (r *Recorder) Emit(instance uintptr, detailedSignal string, args ...uintptr) uintptr {
+	r.mutex.Lock()
+	var handlers []*signalHandler
+	for _, h := range r.handlers {
+		if h.instance == instance && h.signal == detailedSignal {
+			handlers = append(handlers, h)
+		}
+	}
+	r.mutex.Unlock()
+
+	var ret uintptr
+	for _, h := range handlers {
+		values := append(append([]uintptr{instance}, args...), h.data)
+		t := h.handler.Type()
+		if t.NumIn() != len(values) {
+			panic(fmt.Errorf("handler for %s takes %d arguments, got %d", detailedSignal, t.NumIn(), len(values)))
+		}
+
+		in := make([]reflect.Value, len(values))
+		for i, v := range values {
+			in[i] = convertArg(v, t.In(i))
+		}
+
+		out := h.handler.Call(in)
+		if len(out) == 1 {
+			ret = convertRet(out[0])
+		}
+	}
+	return ret
+}
+
+// ScriptMessage creates a script message holding s, as it is passed to
+// script-message-received handlers.
+func (r *Recorder) ScriptMessage(s string) webkitgtk.WebKitJavascriptResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	handle := r.newHandle()
+	r.values[handle] = s
+	return webkitgtk.WebKitJavascriptResult(handle)
+}
+
+// NewString creates a char* holding s, to be passed to Emit.
+func (r *Recorder) NewString(s string) uintptr {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	handle := r.newHandle()
+	r.values[handle] = s
+	return handle
+}
+
+// NewError creates a GError* holding err, to be passed to Emit.
+func (r *Recorder) NewError(err *webkitgtk.GError) uintptr {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	handle := r.newHandle()
+	r.errors[handle] = err
+	return handle
+}
+
+// NewEvent creates a GdkEvent* holding e, which is a
+// webkitgtk.GdkEventConfigure or webkitgtk.GdkEventWindowState, to be passed
+// to Emit.
+func (r *Recorder) NewEvent(e interface{}) uintptr {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	handle := r.newHandle()
+	r.events[handle] = e
+	return handle
+}
+
+// SetWindowState sets the state reported by GtkWindowIsActive,
+// GtkWindowIsMaximized and GtkWindowIsFullscreen. GDK_WINDOW_STATE_FOCUSED
+// marks the window as active.
+func (r *Recorder) SetWindowState(window webkitgtk.GtkWindow, state webkitgtk.GdkWindowState) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.states[uintptr(window)] = state
+}
+
+// NewPolicyDecision creates a policy decision for uri, to be passed to
+// decide-policy handlers with Emit. mimeType is only used by response
+// decisions.
+func (r *Recorder) NewPolicyDecision(uri string, userGesture bool, mimeType string) webkitgtk.WebKitPolicyDecision {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	handle := r.newHandle()
+	r.requests[handle] = request{
+		uri:         uri,
+		userGesture: userGesture,
+		mimeType:    mimeType,
+	}
+	return webkitgtk.WebKitPolicyDecision(handle)
+}
+
+// NewSubframeDecision is like NewPolicyDecision, for a request made by an
+// iframe rather than the main frame.
+func (r *Recorder) NewSubframeDecision(uri string, userGesture bool, mimeType string) webkitgtk.WebKitPolicyDecision {
+	decision := r.NewPolicyDecision(uri, userGesture, mimeType)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	req := r.requests[uintptr(decision)]
+	req.subframe = true
+	r.requests[uintptr(decision)] = req
+	return decision
+}
+
+// NewSchemeRequest creates a request for uri made by webview, to be passed to
+// the callbacks registered with WebKitWebContextRegisterURIScheme.
+func (r *Recorder) NewSchemeRequest(webview webkitgtk.WebKitWebView, uri string) webkitgtk.WebKitURISchemeRequest {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	handle := r.newHandle()
+	r.requests[handle] = request{
+		uri:     uri,
+		webview: webview,
+	}
+	return webkitgtk.WebKitURISchemeRequest(handle)
+}
+
+// SchemeCallback returns the callback registered for scheme on context, or
+// nil.
+func (r *Recorder) SchemeCallback(context webkitgtk.WebKitWebContext, scheme string) webkitgtk.WebKitURISchemeRequestCallback {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.schemes[schemeKey{context, scheme}].callback
+}
+
+// FinalizeContext drops the URI schemes registered on context, calling their
+// destroy notifications like WebKit does when a context is finalized.
+func (r *Recorder) FinalizeContext(context webkitgtk.WebKitWebContext) {
+	r.mutex.Lock()
+	var finalized []uriScheme
+	for key, s := range r.schemes {
+		if key.context == context {
+			finalized = append(finalized, s)
+			delete(r.schemes, key)
+		}
+	}
+	r.mutex.Unlock()
+
+	for _, s := range finalized {
+		if s.notify != nil {
+			s.notify(s.userData)
+		}
+	}
+}
+
+// RunPending runs the queued idle functions without blocking.
+func (r *Recorder) RunPending() {
+	r.mutex.Lock()
+	idle := r.idle
+	r.idle = nil
+	r.mutex.Unlock()
+
+	for _, f := range idle {
+		f()
+	}
+}
+
+func (r *Recorder) record(name string, args ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.calls = append(r.calls, Call{Name: name, Args: args})
+}
+
+// newHandle must be called with the mutex held.
+func (r *Recorder) newHandle() uintptr {
+	r.next++
+	return r.next
+}
+
+func (r *Recorder) handle(name string, args ...interface{}) uintptr {
+	r.record(name, args...)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.newHandle()
+}
+
+func (r *Recorder) enqueue(f func()) {
+	r.mutex.Lock()
+	r.idle = append(r.idle, f)
+	r.mutex.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Recorder) LoadFunctions() error {
+	return nil
+}
+
+func (r *Recorder) Library() *webkitgtk.Library {
+	return &webkitgtk.Library{Name: "webkitgtktest", ABI: r.ABI}
+}
+
+// CopyString returns the string of a char* created by NewString.
+func (r *Recorder) CopyString(str uintptr) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.values[str]
+}
+
+// CopyError returns the error of a GError* created by NewError.
+func (r *Recorder) CopyError(gerr uintptr) *webkitgtk.GError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.errors[gerr]
+}
+
+// CopyEventConfigure returns the event created by NewEvent.
+func (r *Recorder) CopyEventConfigure(event uintptr) webkitgtk.GdkEventConfigure {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	e, _ := r.events[event].(webkitgtk.GdkEventConfigure)
+	return e
+}
+
+// CopyEventWindowState returns the event created by NewEvent.
+func (r *Recorder) CopyEventWindowState(event uintptr) webkitgtk.GdkEventWindowState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	e, _ := r.events[event].(webkitgtk.GdkEventWindowState)
+	return e
+}
+
+func (r *Recorder) GFree(mem uintptr) {
+	r.record("GFree", mem)
+}
+
+func (r *Recorder) GIdleAddFull(priority int, function webkitgtk.GSourceFunc, data uintptr, notify webkitgtk.GDestroyNotify) {
+	r.record("GIdleAddFull", priority, data)
+
+	var run func()
+	run = func() {
+		if function(data) == webkitgtk.G_SOURCE_CONTINUE {
+			r.enqueue(run)
+		} else if notify != nil {
+			notify(data)
+		}
+	}
+	r.enqueue(run)
+}
+
+func (r *Recorder) GSignalConnectData(instance webkitgtk.GtkWidget, detailedSignal string, cHandler webkitgtk.GCallback, data uintptr, destroyData webkitgtk.GClosureNotify, connectFlags webkitgtk.GConnectFlags) uint32 {
+	r.record("GSignalConnectData", instance, detailedSignal, data, connectFlags)
+
+	handler := reflect.ValueOf(cHandler)
+	if handler.Kind() != reflect.Func {
+		panic(fmt.Errorf("failed to connect %s: signal handler must be a function, got %T", detailedSignal, cHandler))
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	h := &signalHandler{
+		id:       uint32(r.newHandle()),
+		instance: uintptr(instance),
+		signal:   detailedSignal,
+		handler:  handler,
+		data:     data,
+	}
+	r.handlers = append(r.handlers, h)
+	return h.id
+}
+
+func (r *Recorder) GSignalHandlerDisconnect(instance webkitgtk.GtkWidget, handlerID uint32) {
+	r.record("GSignalHandlerDisconnect", instance, handlerID)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, h := range r.handlers {
+		if h.instance == uintptr(instance) && h.id == handlerID {
+			r.handlers = append(r.handlers[:i], r.handlers[i+1:]...)
+			break
+		}
+	}
+}
+
+// Handlers returns the number of signal handlers still connected.
+func (r *Recorder) Handlers() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.handlers)
+}
+
+func (r *Recorder) GCancellableNew() webkitgtk.GCancellable {
+	return webkitgtk.GCancellable(r.handle("GCancellableNew"))
+}
+
+func (r *Recorder) GCancellableCancel(cancellable webkitgtk.GCancellable) {
+	r.record("GCancellableCancel", cancellable)
+}
+
+func (r *Recorder) GObjectRef(object webkitgtk.GObject) {
+	r.record("GObjectRef", object)
+}
+
+func (r *Recorder) GObjectUnref(object webkitgtk.GObject) {
+	r.record("GObjectUnref", object)
+}
+
+// GInputStreamReadAll reads nothing, as if every stream was empty.
+func (r *Recorder) GInputStreamReadAll(stream webkitgtk.GInputStream, buffer []byte) (int, error) {
+	r.record("GInputStreamReadAll", stream)
+	return 0, nil
+}
+
+func (r *Recorder) GUnixInputStreamNew(fd int, closeFd bool) webkitgtk.GInputStream {
+	return webkitgtk.GInputStream(r.handle("GUnixInputStreamNew", fd, closeFd))
+}
+
+// GdkPixbufNewFromBytes returns a new handle for any non-empty data.
+func (r *Recorder) GdkPixbufNewFromBytes(data []byte) (webkitgtk.GdkPixbuf, error) {
+	if len(data) == 0 {
+		r.record("GdkPixbufNewFromBytes", data)
+		return webkitgtk.GdkPixbuf(webkitgtk.NULLPTR), fmt.Errorf("failed to load image: no data")
+	}
+	return webkitgtk.GdkPixbuf(r.handle("GdkPixbufNewFromBytes", data)), nil
+}
+
+func (r *Recorder) GtkContainerAdd(container webkitgtk.GtkContainer, widget webkitgtk.GtkWidget) {
+	r.record("GtkContainerAdd", container, widget)
+}
+
+func (r *Recorder) GtkContainerRemove(container webkitgtk.GtkContainer, widget webkitgtk.GtkWidget) {
+	r.record("GtkContainerRemove", container, widget)
+}
+
+func (r *Recorder) GtkInitCheck() bool {
+	r.record("GtkInitCheck")
+	return !r.NoDisplay
+}
+
+// GtkMain runs idle functions until GtkMainQuit is called.
+func (r *Recorder) GtkMain() {
+	r.record("GtkMain")
+
+	for {
+		r.RunPending()
+
+		r.mutex.Lock()
+		quit := r.quit
+		r.quit = false
+		r.mutex.Unlock()
+		if quit {
+			return
+		}
+
+		<-r.wake
+	}
+}
+
+func (r *Recorder) GtkMainQuit() {
+	r.record("GtkMainQuit")
+
+	r.mutex.Lock()
+	r.quit = true
+	r.mutex.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Recorder) GtkWidgetGrabFocus(widget webkitgtk.GtkWidget) {
+	r.record("GtkWidgetGrabFocus", widget)
+}
+
+func (r *Recorder) GtkWidgetSetSizeRequest(widget webkitgtk.GtkWidget, width, height int) {
+	r.record("GtkWidgetSetSizeRequest", widget, width, height)
+}
+
+func (r *Recorder) GtkWidgetShowAll(widget webkitgtk.GtkWidget) {
+	r.record("GtkWidgetShowAll", widget)
+}
+
+func (r *Recorder) GtkWindowNew(windowType webkitgtk.GtkWindowType) webkitgtk.GtkWidget {
+	return webkitgtk.GtkWidget(r.handle("GtkWindowNew", windowType))
+}
+
+// GtkWindowDestroy emits the destroy signal of window.
+func (r *Recorder) GtkWindowDestroy(window webkitgtk.GtkWindow) {
+	r.record("GtkWindowDestroy", window)
+	r.Emit(uintptr(window), "destroy")
+}
+
+func (r *Recorder) GtkWindowResize(window webkitgtk.GtkWindow, width, height int) {
+	r.record("GtkWindowResize", window, width, height)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sizes[uintptr(window)] = [2]int{width, height}
+}
+
+func (r *Recorder) GtkWindowSetDefaultSize(window webkitgtk.GtkWindow, width, height int) {
+	r.record("GtkWindowSetDefaultSize", window, width, height)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sizes[uintptr(window)] = [2]int{width, height}
+}
+
+func (r *Recorder) GtkWindowSetPosition(window webkitgtk.GtkWindow, position webkitgtk.GtkWindowPosition) {
+	r.record("GtkWindowSetPosition", window, position)
+}
+
+func (r *Recorder) GtkWindowSetIcon(window webkitgtk.GtkWindow, icon webkitgtk.GdkPixbuf) {
+	r.record("GtkWindowSetIcon", window, icon)
+}
+
+// GtkWindowGetSize returns the size last passed to GtkWindowResize or
+// GtkWindowSetDefaultSize.
+func (r *Recorder) GtkWindowGetSize(window webkitgtk.GtkWindow) (width, height int) {
+	r.record("GtkWindowGetSize", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	size := r.sizes[uintptr(window)]
+	return size[0], size[1]
+}
+
+func (r *Recorder) GtkWindowMove(window webkitgtk.GtkWindow, x, y int) {
+	r.record("GtkWindowMove", window, x, y)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.points[uintptr(window)] = [2]int{x, y}
+}
+
+// GtkWindowGetPosition returns the position last passed to GtkWindowMove.
+func (r *Recorder) GtkWindowGetPosition(window webkitgtk.GtkWindow) (x, y int) {
+	r.record("GtkWindowGetPosition", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	point := r.points[uintptr(window)]
+	return point[0], point[1]
+}
+
+func (r *Recorder) GtkWindowMaximize(window webkitgtk.GtkWindow) {
+	r.record("GtkWindowMaximize", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[uintptr(window)] |= webkitgtk.GDK_WINDOW_STATE_MAXIMIZED
+}
+
+func (r *Recorder) GtkWindowUnmaximize(window webkitgtk.GtkWindow) {
+	r.record("GtkWindowUnmaximize", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[uintptr(window)] &^= webkitgtk.GDK_WINDOW_STATE_MAXIMIZED
+}
+
+func (r *Recorder) GtkWindowIconify(window webkitgtk.GtkWindow) {
+	r.record("GtkWindowIconify", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[uintptr(window)] |= webkitgtk.GDK_WINDOW_STATE_ICONIFIED
+}
+
+func (r *Recorder) GtkWindowFullscreen(window webkitgtk.GtkWindow) {
+	r.record("GtkWindowFullscreen", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[uintptr(window)] |= webkitgtk.GDK_WINDOW_STATE_FULLSCREEN
+}
+
+func (r *Recorder) GtkWindowUnfullscreen(window webkitgtk.GtkWindow) {
+	r.record("GtkWindowUnfullscreen", window)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[uintptr(window)] &^= webkitgtk.GDK_WINDOW_STATE_FULLSCREEN
+}
+
+func (r *Recorder) GtkWindowSetKeepAbove(window webkitgtk.GtkWindow, setting bool) {
+	r.record("GtkWindowSetKeepAbove", window, setting)
+}
+
+func (r *Recorder) GtkWindowIsActive(window webkitgtk.GtkWindow) bool {
+	r.record("GtkWindowIsActive", window)
+	return r.windowState(window)&webkitgtk.GDK_WINDOW_STATE_FOCUSED != 0
+}
+
+func (r *Recorder) GtkWindowIsMaximized(window webkitgtk.GtkWindow) bool {
+	r.record("GtkWindowIsMaximized", window)
+	return r.windowState(window)&webkitgtk.GDK_WINDOW_STATE_MAXIMIZED != 0
+}
+
+func (r *Recorder) GtkWindowIsFullscreen(window webkitgtk.GtkWindow) bool {
+	r.record("GtkWindowIsFullscreen", window)
+	return r.windowState(window)&webkitgtk.GDK_WINDOW_STATE_FULLSCREEN != 0
+}
+
+func (r *Recorder) windowState(window webkitgtk.GtkWindow) webkitgtk.GdkWindowState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.states[uintptr(window)]
+}
+
+func (r *Recorder) GtkWindowSetDecorated(window webkitgtk.GtkWindow, setting bool) {
+	r.record("GtkWindowSetDecorated", window, setting)
+}
+
+func (r *Recorder) GtkWindowSetGeometryHints(window webkitgtk.GtkWindow, geometryWidget webkitgtk.GtkWidget, geometry webkitgtk.GdkGeometry, geomMask webkitgtk.GdkWindowHints) {
+	r.record("GtkWindowSetGeometryHints", window, geometryWidget, geometry, geomMask)
+}
+
+func (r *Recorder) GtkWindowSetResizable(window webkitgtk.GtkWindow, resizable bool) {
+	r.record("GtkWindowSetResizable", window, resizable)
+}
+
+func (r *Recorder) GtkWindowSetTitle(window webkitgtk.GtkWindow, title string) {
+	r.record("GtkWindowSetTitle", window, title)
+}
+
+// JsCValueToString returns the string of a value created by ScriptMessage.
+func (r *Recorder) JsCValueToString(value webkitgtk.JSCValue) string {
+	r.record("JsCValueToString", value)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.values[uintptr(value)]
+}
+
+// JsCValueToJSON returns the JSON of a value computed by EvalFunc.
+func (r *Recorder) JsCValueToJSON(value webkitgtk.JSCValue, indent uint) string {
+	r.record("JsCValueToJSON", value, indent)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.values[uintptr(value)]
+}
+
+func (r *Recorder) WebKitGetMajorVersion() uint32 {
+	return r.Major
+}
+
+func (r *Recorder) WebKitGetMinorVersion() uint32 {
+	return r.Minor
+}
+
+func (r *Recorder) WebKitGetMicroVersion() uint32 {
+	return r.Micro
+}
+
+// WebKitWebViewNew returns a new handle for a web view using the default web
+// context.
+func (r *Recorder) WebKitWebViewNew() webkitgtk.GtkWidget {
+	r.record("WebKitWebViewNew")
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	webview := r.newHandle()
+	r.contexts[webview] = r.defaultContext()
+	return webkitgtk.GtkWidget(webview)
+}
+
+// defaultContext must be called with the mutex held.
+func (r *Recorder) defaultContext() webkitgtk.WebKitWebContext {
+	if r.context == webkitgtk.WebKitWebContext(webkitgtk.NULLPTR) {
+		r.context = webkitgtk.WebKitWebContext(r.newHandle())
+	}
+	return r.context
+}
+
+// WebKitNetworkSessionGetDefault returns the handle of the default web
+// context, like the 4.x ABIs do.
+func (r *Recorder) WebKitNetworkSessionGetDefault() webkitgtk.WebKitNetworkSession {
+	r.record("WebKitNetworkSessionGetDefault")
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return webkitgtk.WebKitNetworkSession(r.defaultContext())
+}
+
+func (r *Recorder) WebKitNetworkSessionNew(dataDirectory string, cacheDirectory string) webkitgtk.WebKitNetworkSession {
+	return webkitgtk.WebKitNetworkSession(r.handle("WebKitNetworkSessionNew", dataDirectory, cacheDirectory))
+}
+
+func (r *Recorder) WebKitNetworkSessionNewEphemeral() webkitgtk.WebKitNetworkSession {
+	return webkitgtk.WebKitNetworkSession(r.handle("WebKitNetworkSessionNewEphemeral"))
+}
+
+func (r *Recorder) WebKitNetworkSessionGetCookieManager(session webkitgtk.WebKitNetworkSession) webkitgtk.WebKitCookieManager {
+	return webkitgtk.WebKitCookieManager(r.handle("WebKitNetworkSessionGetCookieManager", session))
+}
+
+// WebKitWebViewNewWithNetworkSession returns a new handle for a web view using
+// session as its web context.
+func (r *Recorder) WebKitWebViewNewWithNetworkSession(session webkitgtk.WebKitNetworkSession) webkitgtk.GtkWidget {
+	r.record("WebKitWebViewNewWithNetworkSession", session)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	webview := r.newHandle()
+	r.contexts[webview] = webkitgtk.WebKitWebContext(session)
+	return webkitgtk.GtkWidget(webview)
+}
+
+func (r *Recorder) WebKitNetworkSessionGetWebsiteDataManager(session webkitgtk.WebKitNetworkSession) webkitgtk.WebKitWebsiteDataManager {
+	return webkitgtk.WebKitWebsiteDataManager(r.handle("WebKitNetworkSessionGetWebsiteDataManager", session))
+}
+
+// WebKitWebsiteDataManagerClear empties the cookie jar shared by all
+// cookie managers if types includes cookies.
+func (r *Recorder) WebKitWebsiteDataManagerClear(manager webkitgtk.WebKitWebsiteDataManager, types webkitgtk.WebKitWebsiteDataTypes, timespan int64, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback, userData uintptr) {
+	r.record("WebKitWebsiteDataManagerClear", manager, types, timespan, cancellable, userData)
+
+	r.mutex.Lock()
+	if types&webkitgtk.WEBKIT_WEBSITE_DATA_COOKIES != 0 {
+		r.jar = nil
+	}
+	r.mutex.Unlock()
+
+	r.complete(webkitgtk.GObject(manager), callback, userData)
+}
+
+func (r *Recorder) WebKitWebsiteDataManagerClearFinish(manager webkitgtk.WebKitWebsiteDataManager, result webkitgtk.GAsyncResult) error {
+	r.record("WebKitWebsiteDataManagerClearFinish", manager, result)
+	return nil
+}
+
+func (r *Recorder) WebKitCookieManagerSetAcceptPolicy(manager webkitgtk.WebKitCookieManager, policy webkitgtk.WebKitCookieAcceptPolicy) {
+	r.record("WebKitCookieManagerSetAcceptPolicy", manager, policy)
+}
+
+// WebKitCookieManagerGetCookies returns the cookies of the jar whose domain
+// and path match uri.
+func (r *Recorder) WebKitCookieManagerGetCookies(manager webkitgtk.WebKitCookieManager, uri string, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback, userData uintptr) {
+	r.record("WebKitCookieManagerGetCookies", manager, uri, cancellable, userData)
+	if callback == nil {
+		return
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		panic(err)
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	r.mutex.Lock()
+	var cookies []webkitgtk.SoupCookieData
+	for _, cookie := range r.jar {
+		domain := strings.TrimPrefix(cookie.Domain, ".")
+		if (u.Hostname() == domain || strings.HasSuffix(u.Hostname(), "."+domain)) && strings.HasPrefix(path, cookie.Path) {
+			cookies = append(cookies, cookie)
+		}
+	}
+	res := r.newHandle()
+	r.lists[res] = cookies
+	r.mutex.Unlock()
+
+	r.enqueue(func() {
+		callback(webkitgtk.GObject(manager), webkitgtk.GAsyncResult(res), userData)
+	})
+}
+
+func (r *Recorder) WebKitCookieManagerGetCookiesFinish(manager webkitgtk.WebKitCookieManager, result webkitgtk.GAsyncResult) ([]webkitgtk.SoupCookieData, error) {
+	r.record("WebKitCookieManagerGetCookiesFinish", manager, result)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cookies := r.lists[uintptr(result)]
+	delete(r.lists, uintptr(result))
+	return cookies, nil
+}
+
+// WebKitCookieManagerAddCookie replaces the cookie with the same name,
+// domain and path in the jar.
+func (r *Recorder) WebKitCookieManagerAddCookie(manager webkitgtk.WebKitCookieManager, cookie webkitgtk.SoupCookie, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback, userData uintptr) {
+	r.record("WebKitCookieManagerAddCookie", manager, cookie, cancellable, userData)
+
+	r.mutex.Lock()
+	data := r.cookies[uintptr(cookie)]
+	r.jar = append(removeCookie(r.jar, data), data)
+	r.mutex.Unlock()
+
+	r.complete(webkitgtk.GObject(manager), callback, userData)
+}
+
+func (r *Recorder) WebKitCookieManagerAddCookieFinish(manager webkitgtk.WebKitCookieManager, result webkitgtk.GAsyncResult) error {
+	r.record("WebKitCookieManagerAddCookieFinish", manager, result)
+	return nil
+}
+
+func (r *Recorder) WebKitCookieManagerDeleteCookie(manager webkitgtk.WebKitCookieManager, cookie webkitgtk.SoupCookie, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback, userData uintptr) {
+	r.record("WebKitCookieManagerDeleteCookie", manager, cookie, cancellable, userData)
+
+	r.mutex.Lock()
+	r.jar = removeCookie(r.jar, r.cookies[uintptr(cookie)])
+	r.mutex.Unlock()
+
+	r.complete(webkitgtk.GObject(manager), callback, userData)
+}
+
+func (r *Recorder) WebKitCookieManagerDeleteCookieFinish(manager webkitgtk.WebKitCookieManager, result webkitgtk.GAsyncResult) error {
+	r.record("WebKitCookieManagerDeleteCookieFinish", manager, result)
+	return nil
+}
+
+// removeCookie returns jar without the cookies matching the name, domain
+// and path of cookie.
+func removeCookie(jar []webkitgtk.SoupCookieData, cookie webkitgtk.SoupCookieData) []webkitgtk.SoupCookieData {
+	var kept []webkitgtk.SoupCookieData
+	for _, c := range jar {
+		if c.Name != cookie.Name || c.Domain != cookie.Domain || c.Path != cookie.Path {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// complete runs the callback of an asynchronous operation that always
+// succeeds on the fake main loop.
+func (r *Recorder) complete(source webkitgtk.GObject, callback webkitgtk.GAsyncReadyCallback, userData uintptr) {
+	if callback == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	res := r.newHandle()
+	r.mutex.Unlock()
+
+	r.enqueue(func() {
+		callback(source, webkitgtk.GAsyncResult(res), userData)
+	})
+}
+
+func (r *Recorder) WebKitCookieManagerSetPersistentStorage(manager webkitgtk.WebKitCookieManager, filename string, storage webkitgtk.WebKitCookiePersistentStorage) {
+	r.record("WebKitCookieManagerSetPersistentStorage", manager, filename, storage)
+}
+
+func (r *Recorder) WebKitWebViewGetUserContentManager(webview webkitgtk.WebKitWebView) webkitgtk.WebKitUserContentManager {
+	r.record("WebKitWebViewGetUserContentManager", webview)
+	// The manager shares the handle of its webview, so that it's the same
+	// object every time.
+	return webkitgtk.WebKitUserContentManager(webview)
+}
+
+func (r *Recorder) WebKitWebViewGetSettings(webview webkitgtk.WebKitWebView) webkitgtk.WebKitSettings {
+	r.record("WebKitWebViewGetSettings", webview)
+	return webkitgtk.WebKitSettings(webview)
+}
+
+func (r *Recorder) WebKitWebViewLoadURI(webview webkitgtk.WebKitWebView, uri string) {
+	r.record("WebKitWebViewLoadURI", webview, uri)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.uris[uintptr(webview)] = uri
+}
+
+func (r *Recorder) WebKitWebViewLoadHTML(webview webkitgtk.WebKitWebView, content string, baseUri string) {
+	r.record("WebKitWebViewLoadHTML", webview, content, baseUri)
+}
+
+func (r *Recorder) WebKitWebViewLoadAlternateHTML(webview webkitgtk.WebKitWebView, content string, contentUri string, baseUri string) {
+	r.record("WebKitWebViewLoadAlternateHTML", webview, content, contentUri, baseUri)
+}
+
+// WebKitWebViewGetURI returns the URI last passed to WebKitWebViewLoadURI.
+func (r *Recorder) WebKitWebViewGetURI(webview webkitgtk.WebKitWebView) string {
+	r.record("WebKitWebViewGetURI", webview)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.uris[uintptr(webview)]
+}
+
+func (r *Recorder) WebKitWebViewGetMainResource(webview webkitgtk.WebKitWebView) webkitgtk.WebKitWebResource {
+	r.record("WebKitWebViewGetMainResource", webview)
+	return webkitgtk.WebKitWebResource(webview)
+}
+
+func (r *Recorder) WebKitWebResourceGetResponse(resource webkitgtk.WebKitWebResource) webkitgtk.WebKitURIResponse {
+	r.record("WebKitWebResourceGetResponse", resource)
+	return webkitgtk.WebKitURIResponse(resource)
+}
+
+func (r *Recorder) WebKitURIResponseGetStatusCode(response webkitgtk.WebKitURIResponse) uint {
+	r.record("WebKitURIResponseGetStatusCode", response)
+	return r.StatusCode
+}
+
+func (r *Recorder) WebKitURIResponseGetMIMEType(response webkitgtk.WebKitURIResponse) string {
+	r.record("WebKitURIResponseGetMIMEType", response)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requests[uintptr(response)].mimeType
+}
+
+func (r *Recorder) WebKitURIRequestGetURI(request webkitgtk.WebKitURIRequest) string {
+	r.record("WebKitURIRequestGetURI", request)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requests[uintptr(request)].uri
+}
+
+func (r *Recorder) WebKitPolicyDecisionUse(decision webkitgtk.WebKitPolicyDecision) {
+	r.record("WebKitPolicyDecisionUse", decision)
+}
+
+func (r *Recorder) WebKitPolicyDecisionIgnore(decision webkitgtk.WebKitPolicyDecision) {
+	r.record("WebKitPolicyDecisionIgnore", decision)
+}
+
+func (r *Recorder) WebKitNavigationPolicyDecisionGetNavigationAction(decision webkitgtk.WebKitPolicyDecision) webkitgtk.WebKitNavigationAction {
+	r.record("WebKitNavigationPolicyDecisionGetNavigationAction", decision)
+	return webkitgtk.WebKitNavigationAction(decision)
+}
+
+func (r *Recorder) WebKitNavigationActionGetRequest(action webkitgtk.WebKitNavigationAction) webkitgtk.WebKitURIRequest {
+	r.record("WebKitNavigationActionGetRequest", action)
+	return webkitgtk.WebKitURIRequest(action)
+}
+
+func (r *Recorder) WebKitNavigationActionIsUserGesture(action webkitgtk.WebKitNavigationAction) bool {
+	r.record("WebKitNavigationActionIsUserGesture", action)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requests[uintptr(action)].userGesture
+}
+
+func (r *Recorder) WebKitResponsePolicyDecisionGetRequest(decision webkitgtk.WebKitPolicyDecision) webkitgtk.WebKitURIRequest {
+	r.record("WebKitResponsePolicyDecisionGetRequest", decision)
+	return webkitgtk.WebKitURIRequest(decision)
+}
+
+func (r *Recorder) WebKitResponsePolicyDecisionGetResponse(decision webkitgtk.WebKitPolicyDecision) webkitgtk.WebKitURIResponse {
+	r.record("WebKitResponsePolicyDecisionGetResponse", decision)
+	return webkitgtk.WebKitURIResponse(decision)
+}
+
+func (r *Recorder) WebKitResponsePolicyDecisionIsMainFrameMainResource(decision webkitgtk.WebKitPolicyDecision) bool {
+	r.record("WebKitResponsePolicyDecisionIsMainFrameMainResource", decision)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return !r.requests[uintptr(decision)].subframe
+}
+
+// WebKitWebViewRunJavascript evaluates script with EvalFunc and completes
+// on the main loop.
+func (r *Recorder) WebKitWebViewRunJavascript(webview webkitgtk.WebKitWebView, script string, cancellable webkitgtk.GCancellable, callback webkitgtk.GAsyncReadyCallback, userData uintptr) {
+	r.record("WebKitWebViewRunJavascript", webview, script, cancellable, userData)
+	if callback == nil {
+		return
+	}
+
+	var value string
+	var err error
+	if r.EvalFunc != nil {
+		value, err = r.EvalFunc(script)
+	}
+
+	r.mutex.Lock()
+	res := r.newHandle()
+	if err != nil {
+		r.results[res] = evalResult{err: err}
+	} else {
+		handle := r.newHandle()
+		r.values[handle] = value
+		r.results[res] = evalResult{value: webkitgtk.WebKitJavascriptResult(handle)}
+	}
+	r.mutex.Unlock()
+
+	r.enqueue(func() {
+		callback(webkitgtk.GObject(webview), webkitgtk.GAsyncResult(res), userData)
+	})
+}
+
+func (r *Recorder) WebKitWebViewRunJavascriptFinish(webview webkitgtk.WebKitWebView, result webkitgtk.GAsyncResult) (webkitgtk.WebKitJavascriptResult, error) {
+	r.record("WebKitWebViewRunJavascriptFinish", webview, result)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	res := r.results[uintptr(result)]
+	delete(r.results, uintptr(result))
+	return res.value, res.err
+}
+
+// WebKitJavascriptResultGetJsValue returns a value sharing the handle of
+// jsResult.
+func (r *Recorder) WebKitJavascriptResultGetJsValue(jsResult webkitgtk.WebKitJavascriptResult) webkitgtk.JSCValue {
+	r.record("WebKitJavascriptResultGetJsValue", jsResult)
+	return webkitgtk.JSCValue(jsResult)
+}
+
+func (r *Recorder) WebKitJavascriptResultUnref(jsResult webkitgtk.WebKitJavascriptResult) {
+	r.record("WebKitJavascriptResultUnref", jsResult)
+}
+
+func (r *Recorder) WebKitUserContentManagerAddScript(manager webkitgtk.WebKitUserContentManager, script webkitgtk.WebKitUserScript) {
+	r.record("WebKitUserContentManagerAddScript", manager, script)
+}
+
+func (r *Recorder) WebKitUserContentManagerRemoveAllScripts(manager webkitgtk.WebKitUserContentManager) {
+	r.record("WebKitUserContentManagerRemoveAllScripts", manager)
+}
+
+func (r *Recorder) WebKitUserContentManagerUnregisterScriptMessageHandler(manager webkitgtk.WebKitUserContentManager, name string) {
+	r.record("WebKitUserContentManagerUnregisterScriptMessageHandler", manager, name)
+}
+
+func (r *Recorder) WebKitUserContentManagerRegisterScriptMessageHandler(manager webkitgtk.WebKitUserContentManager, name string) {
+	r.record("WebKitUserContentManagerRegisterScriptMessageHandler", manager, name)
+}
+
+func (r *Recorder) WebKitUserScriptNew(source string, injectedFrames webkitgtk.WebKitUserContentInjectedFrames, injectionTime webkitgtk.WebKitUserScriptInjectionTime, allowList []string, blockList []string) webkitgtk.WebKitUserScript {
+	return webkitgtk.WebKitUserScript(r.handle("WebKitUserScriptNew", source, injectedFrames, injectionTime, allowList, blockList))
+}
+
+func (r *Recorder) WebKitUserScriptUnref(script webkitgtk.WebKitUserScript) {
+	r.record("WebKitUserScriptUnref", script)
+}
+
+func (r *Recorder) WebKitSettingsSetEnableDeveloperExtras(settings webkitgtk.WebKitSettings, enabled bool) {
+	r.record("WebKitSettingsSetEnableDeveloperExtras", settings, enabled)
+}
+
+func (r *Recorder) WebKitSettingsSetEnableWriteConsoleMessagesToStdout(settings webkitgtk.WebKitSettings, enabled bool) {
+	r.record("WebKitSettingsSetEnableWriteConsoleMessagesToStdout", settings, enabled)
+}
+
+func (r *Recorder) WebKitSettingsSetJavascriptCanAccessClipboard(settings webkitgtk.WebKitSettings, enabled bool) {
+	r.record("WebKitSettingsSetJavascriptCanAccessClipboard", settings, enabled)
+}
+
+func (r *Recorder) WebKitWebViewGetContext(webview webkitgtk.WebKitWebView) webkitgtk.WebKitWebContext {
+	r.record("WebKitWebViewGetContext", webview)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.contexts[uintptr(webview)]
+}
+
+func (r *Recorder) WebKitWebContextRegisterURIScheme(context webkitgtk.WebKitWebContext, scheme string, callback webkitgtk.WebKitURISchemeRequestCallback, userData uintptr, notify webkitgtk.GDestroyNotify) {
+	r.record("WebKitWebContextRegisterURIScheme", context, scheme, userData)
+
+	r.mutex.Lock()
+	key := schemeKey{context, scheme}
+	old, replaced := r.schemes[key]
+	r.schemes[key] = uriScheme{callback, userData, notify}
+	r.mutex.Unlock()
+
+	if replaced && old.notify != nil {
+		old.notify(old.userData)
+	}
+}
+
+func (r *Recorder) WebKitWebContextGetSecurityManager(context webkitgtk.WebKitWebContext) webkitgtk.WebKitSecurityManager {
+	r.record("WebKitWebContextGetSecurityManager", context)
+	return webkitgtk.WebKitSecurityManager(context)
+}
+
+func (r *Recorder) WebKitSecurityManagerRegisterURISchemeAsSecure(manager webkitgtk.WebKitSecurityManager, scheme string) {
+	r.record("WebKitSecurityManagerRegisterURISchemeAsSecure", manager, scheme)
+}
+
+func (r *Recorder) WebKitSecurityManagerRegisterURISchemeAsCorsEnabled(manager webkitgtk.WebKitSecurityManager, scheme string) {
+	r.record("WebKitSecurityManagerRegisterURISchemeAsCorsEnabled", manager, scheme)
+}
+
+func (r *Recorder) WebKitURISchemeRequestGetURI(request webkitgtk.WebKitURISchemeRequest) string {
+	r.record("WebKitURISchemeRequestGetURI", request)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requests[uintptr(request)].uri
+}
+
+func (r *Recorder) WebKitURISchemeRequestGetWebView(request webkitgtk.WebKitURISchemeRequest) webkitgtk.WebKitWebView {
+	r.record("WebKitURISchemeRequestGetWebView", request)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requests[uintptr(request)].webview
+}
+
+func (r *Recorder) WebKitURISchemeRequestGetHTTPMethod(request webkitgtk.WebKitURISchemeRequest) string {
+	r.record("WebKitURISchemeRequestGetHTTPMethod", request)
+	return ""
+}
+
+func (r *Recorder) WebKitURISchemeRequestGetHTTPHeaders(request webkitgtk.WebKitURISchemeRequest) webkitgtk.SoupMessageHeaders {
+	r.record("WebKitURISchemeRequestGetHTTPHeaders", request)
+	return webkitgtk.SoupMessageHeaders(webkitgtk.NULLPTR)
+}
+
+func (r *Recorder) WebKitURISchemeRequestGetHTTPBody(request webkitgtk.WebKitURISchemeRequest) webkitgtk.GInputStream {
+	r.record("WebKitURISchemeRequestGetHTTPBody", request)
+	return webkitgtk.GInputStream(webkitgtk.NULLPTR)
+}
+
+func (r *Recorder) WebKitURISchemeRequestFinish(request webkitgtk.WebKitURISchemeRequest, stream webkitgtk.GInputStream, length int64, contentType string) {
+	r.record("WebKitURISchemeRequestFinish", request, stream, length, contentType)
+}
+
+func (r *Recorder) WebKitURISchemeRequestFinishWithResponse(request webkitgtk.WebKitURISchemeRequest, response webkitgtk.WebKitURISchemeResponse) {
+	r.record("WebKitURISchemeRequestFinishWithResponse", request, response)
+}
+
+func (r *Recorder) WebKitURISchemeRequestFinishError(request webkitgtk.WebKitURISchemeRequest, message string) {
+	r.record("WebKitURISchemeRequestFinishError", request, message)
+}
+
+// WebKitURISchemeResponseNew returns NULLPTR before WebKitGTK 2.36.
+func (r *Recorder) WebKitURISchemeResponseNew(stream webkitgtk.GInputStream, length int64) webkitgtk.WebKitURISchemeResponse {
+	if r.Major == 2 && r.Minor < 36 {
+		r.record("WebKitURISchemeResponseNew", stream, length)
+		return webkitgtk.WebKitURISchemeResponse(webkitgtk.NULLPTR)
+	}
+	return webkitgtk.WebKitURISchemeResponse(r.handle("WebKitURISchemeResponseNew", stream, length))
+}
+
+func (r *Recorder) WebKitURISchemeResponseSetStatus(response webkitgtk.WebKitURISchemeResponse, statusCode int, reasonPhrase string) {
+	r.record("WebKitURISchemeResponseSetStatus", response, statusCode, reasonPhrase)
+}
+
+func (r *Recorder) WebKitURISchemeResponseSetContentType(response webkitgtk.WebKitURISchemeResponse, contentType string) {
+	r.record("WebKitURISchemeResponseSetContentType", response, contentType)
+}
+
+func (r *Recorder) WebKitURISchemeResponseSetHTTPHeaders(response webkitgtk.WebKitURISchemeResponse, headers webkitgtk.SoupMessageHeaders) {
+	r.record("WebKitURISchemeResponseSetHTTPHeaders", response, headers)
+}
+
+func (r *Recorder) SoupMessageHeadersNew(headersType webkitgtk.SoupMessageHeadersType) webkitgtk.SoupMessageHeaders {
+	return webkitgtk.SoupMessageHeaders(r.handle("SoupMessageHeadersNew", headersType))
+}
+
+func (r *Recorder) SoupMessageHeadersAppend(headers webkitgtk.SoupMessageHeaders, name string, value string) {
+	r.record("SoupMessageHeadersAppend", headers, name, value)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.headers[uintptr(headers)] = append(r.headers[uintptr(headers)], [2]string{name, value})
+}
+
+func (r *Recorder) SoupMessageHeadersForeach(headers webkitgtk.SoupMessageHeaders, f func(name string, value string)) {
+	r.record("SoupMessageHeadersForeach", headers)
+
+	r.mutex.Lock()
+	pairs := append([][2]string(nil), r.headers[uintptr(headers)]...)
+	r.mutex.Unlock()
+
+	for _, pair := range pairs {
+		f(pair[0], pair[1])
+	}
+}
+
+// SoupCookieNew stores cookie for the cookie manager functions.
+func (r *Recorder) SoupCookieNew(cookie webkitgtk.SoupCookieData) webkitgtk.SoupCookie {
+	handle := r.handle("SoupCookieNew", cookie)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cookies[handle] = cookie
+	return webkitgtk.SoupCookie(handle)
+}
+
+func (r *Recorder) SoupCookieFree(cookie webkitgtk.SoupCookie) {
+	r.record("SoupCookieFree", cookie)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.cookies, uintptr(cookie))
+}
+
+// convertArg converts a raw argument to the type a handler expects, like
+// the native trampolines do.
+func convertArg(v uintptr, t reflect.Type) reflect.Value {
+	switch t.Kind() {
+	case reflect.Bool:
+		return reflect.ValueOf(uint32(v) != 0).Convert(t)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(int64(v)).Convert(t)
+	default:
+		return reflect.ValueOf(v).Convert(t)
+	}
+}
+
+func convertRet(v reflect.Value) uintptr {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uintptr(v.Int())
+	default:
+		return uintptr(v.Uint())
+	}
+}
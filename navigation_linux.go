@@ -0,0 +1,111 @@
+//go:build linux
+
+package webview
+
+import (
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+func (w *webview) OnNavigation(f func(e *NavigationEvent)) (off func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.nextHandler++
+	id := w.nextHandler
+	w.navigationHandlers[id] = f
+
+	return func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.navigationHandlers, id)
+	}
+}
+
+// connectNavigation reports the load-changed and load-failed signals of the
+// webview as navigation events.
+func (w *webview) connectNavigation() {
+	w.connect(webkitgtk.GtkWidget(w.webview), "load-changed", func(webview webkitgtk.WebKitWebView, loadEvent webkitgtk.WebKitLoadEvent, arg uintptr) {
+		e := &NavigationEvent{
+			URL: w.webkit.WebKitWebViewGetURI(webview),
+		}
+		switch loadEvent {
+		case webkitgtk.WEBKIT_LOAD_STARTED:
+			// Calls made by the previous page can no longer be answered.
+			w.bridge.CancelPendingCalls()
+			e.Type = NavigationStarted
+		case webkitgtk.WEBKIT_LOAD_REDIRECTED:
+			e.Type = NavigationRedirected
+		case webkitgtk.WEBKIT_LOAD_COMMITTED:
+			e.Type = NavigationCommitted
+			e.StatusCode = w.statusCode()
+		case webkitgtk.WEBKIT_LOAD_FINISHED:
+			e.Type = NavigationFinished
+			e.StatusCode = w.statusCode()
+		default:
+			return
+		}
+		w.emitNavigation(e)
+	})
+
+	w.connect(webkitgtk.GtkWidget(w.webview), "load-failed", func(webview webkitgtk.WebKitWebView, loadEvent webkitgtk.WebKitLoadEvent, failingURI uintptr, gerr uintptr, arg uintptr) bool {
+		e := &NavigationEvent{
+			Type: NavigationFailed,
+			URL:  w.webkit.CopyString(failingURI),
+		}
+		if err := w.webkit.CopyError(gerr); err != nil {
+			// Loads stopped by a navigation policy, a download or a new
+			// navigation didn't fail.
+			if interrupted(err) {
+				return false
+			}
+			e.Err = &NavigationError{Domain: err.Domain, Code: err.Code, Message: err.Message}
+		}
+		w.emitNavigation(e)
+
+		// Returning true stops WebKit from showing its own error page.
+		if e.ErrorHTML == "" {
+			return false
+		}
+		w.webkit.WebKitWebViewLoadAlternateHTML(webview, e.ErrorHTML, e.URL, "")
+		return true
+	})
+}
+
+// interrupted reports whether err is the error of a load that was cancelled,
+// or stopped by a policy decision.
+func interrupted(err *webkitgtk.GError) bool {
+	switch err.Domain {
+	case webkitgtk.WEBKIT_NETWORK_ERROR:
+		return err.Code == webkitgtk.WEBKIT_NETWORK_ERROR_CANCELLED
+	case webkitgtk.WEBKIT_POLICY_ERROR:
+		return err.Code == webkitgtk.WEBKIT_POLICY_ERROR_FRAME_LOAD_INTERRUPTED_BY_POLICY_CHANGE
+	}
+	return false
+}
+
+func (w *webview) emitNavigation(e *NavigationEvent) {
+	w.mutex.Lock()
+	handlers := make([]func(*NavigationEvent), 0, len(w.navigationHandlers))
+	for _, f := range w.navigationHandlers {
+		handlers = append(handlers, f)
+	}
+	w.mutex.Unlock()
+
+	for _, f := range handlers {
+		f(e)
+	}
+}
+
+// statusCode returns the HTTP status of the main resource, or 0 if there is
+// no response yet.
+func (w *webview) statusCode() int {
+	resource := w.webkit.WebKitWebViewGetMainResource(w.webview)
+	if resource == webkitgtk.WebKitWebResource(webkitgtk.NULLPTR) {
+		return 0
+	}
+	response := w.webkit.WebKitWebResourceGetResponse(resource)
+	if response == webkitgtk.WebKitURIResponse(webkitgtk.NULLPTR) {
+		return 0
+	}
+	return int(w.webkit.WebKitURIResponseGetStatusCode(response))
+}
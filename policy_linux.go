@@ -0,0 +1,83 @@
+//go:build linux
+
+package webview
+
+import (
+	"os/exec"
+
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+// openExternal opens url in the default application of the desktop.
+var openExternal = func(url string) error {
+	cmd := exec.Command("xdg-open", url)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+func (w *webview) SetNavigationPolicy(f func(d *PolicyDecision) Policy) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.navigationPolicy = f
+}
+
+// connectPolicy applies the navigation policy to the decide-policy signal of
+// the webview.
+func (w *webview) connectPolicy() {
+	w.connect(webkitgtk.GtkWidget(w.webview), "decide-policy", func(webview webkitgtk.WebKitWebView, decision webkitgtk.WebKitPolicyDecision, decisionType webkitgtk.WebKitPolicyDecisionType, arg uintptr) bool {
+		w.mutex.Lock()
+		policy := w.navigationPolicy
+		w.mutex.Unlock()
+		if policy == nil {
+			return false
+		}
+
+		d := &PolicyDecision{}
+		switch decisionType {
+		case webkitgtk.WEBKIT_POLICY_DECISION_TYPE_NAVIGATION_ACTION, webkitgtk.WEBKIT_POLICY_DECISION_TYPE_NEW_WINDOW_ACTION:
+			d.Type = DecisionNavigation
+			if decisionType == webkitgtk.WEBKIT_POLICY_DECISION_TYPE_NEW_WINDOW_ACTION {
+				d.Type = DecisionNewWindow
+			}
+			action := w.webkit.WebKitNavigationPolicyDecisionGetNavigationAction(decision)
+			d.URL = w.webkit.WebKitURIRequestGetURI(w.webkit.WebKitNavigationActionGetRequest(action))
+			d.UserGesture = w.webkit.WebKitNavigationActionIsUserGesture(action)
+		case webkitgtk.WEBKIT_POLICY_DECISION_TYPE_RESPONSE:
+			d.Type = DecisionResponse
+			d.URL = w.webkit.WebKitURIRequestGetURI(w.webkit.WebKitResponsePolicyDecisionGetRequest(decision))
+			response := w.webkit.WebKitResponsePolicyDecisionGetResponse(decision)
+			d.MIMEType = w.webkit.WebKitURIResponseGetMIMEType(response)
+			d.StatusCode = int(w.webkit.WebKitURIResponseGetStatusCode(response))
+			d.MainFrame = w.webkit.WebKitResponsePolicyDecisionIsMainFrameMainResource(decision)
+		default:
+			return false
+		}
+
+		switch policy(d) {
+		case PolicyAllow:
+			switch d.Type {
+			case DecisionNewWindow:
+				// There is no second window to open, so load it here instead.
+				w.webkit.WebKitPolicyDecisionIgnore(decision)
+				w.webkit.WebKitWebViewLoadURI(webview, d.URL)
+			case DecisionResponse:
+				// Let WebKit decide, it downloads what it cannot display.
+				return false
+			default:
+				w.webkit.WebKitPolicyDecisionUse(decision)
+			}
+		case PolicyOpenExternal:
+			// The request is cancelled whether or not the desktop manages to
+			// open URL, and there is nobody left to report a failure to.
+			w.webkit.WebKitPolicyDecisionIgnore(decision)
+			openExternal(d.URL)
+		default:
+			w.webkit.WebKitPolicyDecisionIgnore(decision)
+		}
+		return true
+	})
+}
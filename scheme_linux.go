@@ -0,0 +1,328 @@
+//go:build linux
+
+package webview
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+func (w *webview) RegisterScheme(scheme string, handler http.Handler) error {
+	if handler == nil {
+		return fmt.Errorf("handler must not be nil")
+	}
+	scheme = strings.ToLower(scheme)
+	switch scheme {
+	case "":
+		return fmt.Errorf("scheme must not be empty")
+	case "about", "blob", "data", "file", "ftp", "http", "https", "javascript", "ws", "wss":
+		return fmt.Errorf("scheme %q is reserved", scheme)
+	}
+
+	w.mutex.Lock()
+	w.schemes[scheme] = handler
+	w.mutex.Unlock()
+
+	return registerScheme(w, scheme)
+}
+
+// schemeContexts holds the URI schemes registered on the web contexts of the
+// process and the webviews using them. The default web context is shared by
+// the webviews of every app, so requests are served by the handler of the
+// webview that made them.
+var schemeContexts = struct {
+	sync.Mutex
+	m map[schemeContextKey]*schemeContext
+}{m: make(map[schemeContextKey]*schemeContext)}
+
+// schemeContextKey identifies a web context. Contexts are only unique within
+// the library they come from, which matters to tests using several fakes.
+type schemeContextKey struct {
+	webkit  webkitgtk.Context
+	context webkitgtk.WebKitWebContext
+}
+
+type schemeContext struct {
+	schemes  map[string]bool
+	webviews map[*webview]bool
+}
+
+// registerScheme registers scheme on the web context of w, unless a webview
+// using the context already did.
+func registerScheme(w *webview, scheme string) error {
+	key := schemeContextKey{w.webkit, w.webkit.WebKitWebViewGetContext(w.webview)}
+
+	schemeContexts.Lock()
+	// Checked with the registry locked, so that forgetSchemes can't miss w.
+	if w.isDestroyed() {
+		schemeContexts.Unlock()
+		return ErrDestroyed
+	}
+	entry := schemeContexts.m[key]
+	if entry == nil {
+		entry = &schemeContext{schemes: make(map[string]bool), webviews: make(map[*webview]bool)}
+		schemeContexts.m[key] = entry
+	}
+	entry.webviews[w] = true
+	registered := entry.schemes[scheme]
+	entry.schemes[scheme] = true
+	schemeContexts.Unlock()
+	if registered {
+		return nil
+	}
+
+	// WebKit drops the callback when the context is finalized, after which
+	// its address may be reused by a new context.
+	w.webkit.WebKitWebContextRegisterURIScheme(key.context, scheme, func(request webkitgtk.WebKitURISchemeRequest, userData uintptr) {
+		serveScheme(key, scheme, request)
+	}, webkitgtk.NULLPTR, func(userData uintptr) {
+		schemeContexts.Lock()
+		defer schemeContexts.Unlock()
+		if schemeContexts.m[key] == entry {
+			delete(schemeContexts.m, key)
+		}
+	})
+
+	security := w.webkit.WebKitWebContextGetSecurityManager(key.context)
+	w.webkit.WebKitSecurityManagerRegisterURISchemeAsSecure(security, scheme)
+	w.webkit.WebKitSecurityManagerRegisterURISchemeAsCorsEnabled(security, scheme)
+	return nil
+}
+
+// forgetSchemes stops serving the scheme requests of w, once its web view has
+// been released. Contexts no webview uses anymore are forgotten, registering
+// a scheme on them again replaces the callback.
+func forgetSchemes(w *webview) {
+	schemeContexts.Lock()
+	defer schemeContexts.Unlock()
+
+	for key, entry := range schemeContexts.m {
+		delete(entry.webviews, w)
+		if len(entry.webviews) == 0 {
+			delete(schemeContexts.m, key)
+		}
+	}
+}
+
+// serveScheme passes a request made on the web context of key to the handler
+// the webview that made it registered for scheme.
+func serveScheme(key schemeContextKey, scheme string, request webkitgtk.WebKitURISchemeRequest) {
+	view := key.webkit.WebKitURISchemeRequestGetWebView(request)
+
+	var target *webview
+	schemeContexts.Lock()
+	if entry := schemeContexts.m[key]; entry != nil {
+		for w := range entry.webviews {
+			if w.webview == view {
+				target = w
+				break
+			}
+		}
+	}
+	schemeContexts.Unlock()
+
+	var handler http.Handler
+	if target != nil {
+		target.mutex.Lock()
+		handler = target.schemes[scheme]
+		target.mutex.Unlock()
+	}
+	if handler == nil {
+		key.webkit.WebKitURISchemeRequestFinishError(request, fmt.Sprintf("no handler for scheme %s", scheme))
+		return
+	}
+	target.serveScheme(handler, request)
+}
+
+// serveScheme is called on the main thread for every request to a registered
+// scheme. The handler runs on its own goroutine so it can't block the UI.
+func (w *webview) serveScheme(handler http.Handler, request webkitgtk.WebKitURISchemeRequest) {
+	req, err := w.newSchemeRequest(request)
+	if err != nil {
+		w.webkit.WebKitURISchemeRequestFinishError(request, err.Error())
+		return
+	}
+
+	// Keep the request alive until the response has been handed to WebKit.
+	w.webkit.GObjectRef(webkitgtk.GObject(request))
+	rw := &schemeResponseWriter{
+		w:       w,
+		request: request,
+		header:  make(http.Header),
+	}
+	go func() {
+		defer rw.close()
+		// A panicking handler must not take down the process, nor leave the
+		// request pending.
+		defer func() {
+			if err := recover(); err != nil {
+				rw.abort(fmt.Errorf("scheme handler panicked: %v", err))
+			}
+		}()
+		handler.ServeHTTP(rw, req)
+	}()
+}
+
+func (w *webview) newSchemeRequest(request webkitgtk.WebKitURISchemeRequest) (*http.Request, error) {
+	method := w.webkit.WebKitURISchemeRequestGetHTTPMethod(request)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body []byte
+	if stream := w.webkit.WebKitURISchemeRequestGetHTTPBody(request); stream != webkitgtk.GInputStream(webkitgtk.NULLPTR) {
+		defer w.webkit.GObjectUnref(webkitgtk.GObject(stream))
+
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := w.webkit.GInputStreamReadAll(stream, buf)
+			body = append(body, buf[:n]...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %w", err)
+			}
+			if n < len(buf) {
+				break
+			}
+		}
+	}
+
+	req, err := http.NewRequest(method, w.webkit.WebKitURISchemeRequestGetURI(request), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	if headers := w.webkit.WebKitURISchemeRequestGetHTTPHeaders(request); headers != webkitgtk.SoupMessageHeaders(webkitgtk.NULLPTR) {
+		w.webkit.SoupMessageHeadersForeach(headers, func(name string, value string) {
+			req.Header.Add(name, value)
+		})
+	}
+
+	return req, nil
+}
+
+// schemeResponseWriter is the http.ResponseWriter handed to scheme handlers.
+// The status and headers are sent to WebKit on the first write, after which
+// the body is streamed through a pipe.
+type schemeResponseWriter struct {
+	w       *webview
+	request webkitgtk.WebKitURISchemeRequest
+	header  http.Header
+
+	// body is the write end of the pipe WebKit reads the response from. It is
+	// nil until the headers have been sent.
+	body *os.File
+	err  error
+}
+
+func (rw *schemeResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *schemeResponseWriter) WriteHeader(statusCode int) {
+	if rw.body != nil || rw.err != nil {
+		return
+	}
+
+	r, body, err := os.Pipe()
+	if err != nil {
+		rw.fail(fmt.Errorf("failed to create response pipe: %w", err))
+		return
+	}
+	// WebKit takes ownership of its own copy of the read end.
+	fd, err := syscall.Dup(int(r.Fd()))
+	r.Close()
+	if err != nil {
+		body.Close()
+		rw.fail(fmt.Errorf("failed to create response pipe: %w", err))
+		return
+	}
+	rw.body = body
+
+	header := rw.header.Clone()
+	length := int64(-1)
+	if n, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		length = n
+	}
+
+	request := rw.request
+	w := rw.w
+	// The request must be finished even if the webview is gone.
+	w.app.Dispatch(func() {
+		defer w.webkit.GObjectUnref(webkitgtk.GObject(request))
+
+		stream := w.webkit.GUnixInputStreamNew(fd, true)
+		defer w.webkit.GObjectUnref(webkitgtk.GObject(stream))
+
+		response := w.webkit.WebKitURISchemeResponseNew(stream, length)
+		if response == webkitgtk.WebKitURISchemeResponse(webkitgtk.NULLPTR) {
+			// WebKitGTK < 2.36 has no way to report the status or headers.
+			w.webkit.WebKitURISchemeRequestFinish(request, stream, length, header.Get("Content-Type"))
+			return
+		}
+		defer w.webkit.GObjectUnref(webkitgtk.GObject(response))
+
+		w.webkit.WebKitURISchemeResponseSetStatus(response, statusCode, http.StatusText(statusCode))
+		if contentType := header.Get("Content-Type"); contentType != "" {
+			w.webkit.WebKitURISchemeResponseSetContentType(response, contentType)
+		}
+		headers := w.webkit.SoupMessageHeadersNew(webkitgtk.SOUP_MESSAGE_HEADERS_RESPONSE)
+		for name, values := range header {
+			for _, value := range values {
+				w.webkit.SoupMessageHeadersAppend(headers, name, value)
+			}
+		}
+		w.webkit.WebKitURISchemeResponseSetHTTPHeaders(response, headers)
+		w.webkit.WebKitURISchemeRequestFinishWithResponse(request, response)
+	})
+}
+
+func (rw *schemeResponseWriter) Write(p []byte) (int, error) {
+	if rw.body == nil && rw.err == nil {
+		if rw.header.Get("Content-Type") == "" {
+			rw.header.Set("Content-Type", http.DetectContentType(p))
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.err != nil {
+		return 0, rw.err
+	}
+
+	return rw.body.Write(p)
+}
+
+// close completes the response once the handler has returned.
+func (rw *schemeResponseWriter) close() {
+	if rw.body == nil && rw.err == nil {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.body != nil {
+		rw.body.Close()
+	}
+}
+
+// abort fails the request if no headers have been sent yet, or truncates the
+// response otherwise.
+func (rw *schemeResponseWriter) abort(err error) {
+	if rw.body == nil && rw.err == nil {
+		rw.fail(err)
+	}
+}
+
+// fail aborts the request before any headers have been sent.
+func (rw *schemeResponseWriter) fail(err error) {
+	rw.err = err
+	request := rw.request
+	w := rw.w
+	w.app.Dispatch(func() {
+		w.webkit.WebKitURISchemeRequestFinishError(request, err.Error())
+		w.webkit.GObjectUnref(webkitgtk.GObject(request))
+	})
+}
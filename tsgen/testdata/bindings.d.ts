@@ -0,0 +1,39 @@
+// Code generated by go-webview/tsgen. DO NOT EDIT.
+
+export interface User {
+	id: string;
+	name: string;
+	email?: string;
+	tags: string[] | null;
+	avatar?: string;
+	friends: (User | null)[] | null;
+	created: string;
+	city: string;
+	country: string;
+	extra: Record<string, string> | null;
+}
+
+export interface Page {
+	items: User[] | null;
+	next: number | null;
+}
+
+declare global {
+	interface Window {
+		add(arg0: number, arg1: number): Promise<number>;
+		matrix(arg0: (number[] | null)[] | null, arg1: boolean[]): Promise<Record<string, string[] | null> | null>;
+		"not-an-identifier"(): Promise<void>;
+		notify(arg0: string): Promise<void>;
+		point(arg0: { X: number; Y: number; }): Promise<{ X: number; Y: number; } | null>;
+		raw(arg0: any, arg1: any): Promise<any>;
+		sum(...arg0: number[]): Promise<number>;
+		users: {
+			delete(arg0: number): Promise<void>;
+			get(arg0: number): Promise<User | null>;
+			httpStatus(): Promise<number>;
+			list(arg0: number): Promise<Page>;
+		};
+	}
+}
+
+export {};
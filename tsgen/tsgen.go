@@ -0,0 +1,365 @@
+// Package tsgen generates TypeScript declarations for the functions bound to a
+// webview, so the JavaScript that calls them is checked against their Go
+// signatures.
+//
+// The declarations are derived from the bound functions by reflection. Use
+// webviewtest to collect them without a display:
+//
+//	w := webviewtest.New()
+//	registerBindings(w)
+//	err := tsgen.Generate(f, w.Bindings())
+//
+// The webview-tsgen command does this for a function of another package.
+package tsgen
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	contextType       = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// Generate writes a .d.ts file declaring bindings, the bound functions by name
+// as returned by webviewtest.WebView.Bindings, as members of Window. Names of
+// the form "namespace.method", as bound by WebView.BindObject, are declared as
+// methods of the window[namespace] object.
+//
+// Functions return a Promise of their result. Struct types are declared as
+// interfaces with the fields encoding/json marshals, named after the Go type.
+// Types that implement json.Marshaler are declared as any, except time.Time.
+// Pointers, slices and maps may be null, which is what encoding/json makes of
+// nil values.
+func Generate(w io.Writer, bindings map[string]interface{}) error {
+	g := &generator{
+		names: make(map[reflect.Type]string),
+		taken: make(map[string]bool),
+	}
+
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	globals := make([]string, 0, len(names))
+	namespaces := make(map[string][]string)
+	var order []string
+	for _, name := range names {
+		t := reflect.TypeOf(bindings[name])
+		if t == nil || t.Kind() != reflect.Func {
+			return fmt.Errorf("binding %s is not a function", name)
+		}
+
+		if i := strings.IndexByte(name, '.'); i > 0 {
+			namespace := name[:i]
+			if namespaces[namespace] == nil {
+				order = append(order, namespace)
+			}
+			namespaces[namespace] = append(namespaces[namespace], g.function(name[i+1:], t))
+		} else {
+			globals = append(globals, g.function(name, t))
+		}
+		if g.err != nil {
+			return fmt.Errorf("binding %s: %w", name, g.err)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by go-webview/tsgen. DO NOT EDIT.\n\n")
+	for _, decl := range g.decls {
+		buf.WriteString(decl)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("declare global {\n\tinterface Window {\n")
+	for _, f := range globals {
+		fmt.Fprintf(&buf, "\t\t%s;\n", f)
+	}
+	for _, namespace := range order {
+		fmt.Fprintf(&buf, "\t\t%s: {\n", property(namespace))
+		for _, f := range namespaces[namespace] {
+			fmt.Fprintf(&buf, "\t\t\t%s;\n", f)
+		}
+		buf.WriteString("\t\t};\n")
+	}
+	buf.WriteString("\t}\n}\n\nexport {};\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// generator collects the interfaces of the struct types used by bindings.
+type generator struct {
+	names map[reflect.Type]string
+	taken map[string]bool
+	decls []string
+	err   error
+}
+
+// function returns the method signature of a bound function of type t, with
+// the arguments and result the bridge marshals.
+func (g *generator) function(name string, t reflect.Type) string {
+	offset := 0
+	if t.NumIn() > 0 && t.In(0) == contextType {
+		offset = 1
+	}
+
+	params := make([]string, 0, t.NumIn()-offset)
+	for i := offset; i < t.NumIn(); i++ {
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			params = append(params, fmt.Sprintf("...arg%d: %s", i-offset, arrayOf(g.typeOf(t.In(i).Elem()))))
+		} else {
+			params = append(params, fmt.Sprintf("arg%d: %s", i-offset, g.typeOf(t.In(i))))
+		}
+	}
+
+	result := "void"
+	switch {
+	case t.NumOut() > 2:
+		g.fail(fmt.Errorf("function may only return a value or a value+error"))
+	case t.NumOut() == 2 || (t.NumOut() == 1 && !t.Out(0).Implements(errorType)):
+		result = g.typeOf(t.Out(0))
+	}
+
+	return fmt.Sprintf("%s(%s): Promise<%s>", property(name), strings.Join(params, ", "), result)
+}
+
+// typeOf returns the TypeScript type of the JSON encoding of t.
+func (g *generator) typeOf(t reflect.Type) string {
+	if t == timeType {
+		return "string"
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return "any"
+	}
+	if t.Kind() != reflect.Ptr && (t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Interface:
+		return "any"
+	case reflect.Ptr:
+		return nullable(g.typeOf(t.Elem()))
+	case reflect.Slice:
+		// encoding/json encodes []byte as a base64 string.
+		if t.Elem().Kind() == reflect.Uint8 && !t.Elem().Implements(marshalerType) && !t.Elem().Implements(textMarshalerType) {
+			return "string | null"
+		}
+		return arrayOf(g.typeOf(t.Elem())) + " | null"
+	case reflect.Array:
+		return arrayOf(g.typeOf(t.Elem()))
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				g.fail(fmt.Errorf("unsupported map key type %s", t.Key()))
+			}
+		}
+		return fmt.Sprintf("Record<string, %s> | null", g.typeOf(t.Elem()))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, true)
+		}
+		return g.declare(t)
+	default:
+		g.fail(fmt.Errorf("unsupported type %s", t))
+		return "any"
+	}
+}
+
+// declare adds the interface of the named struct type t, and returns its
+// name.
+func (g *generator) declare(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	// Type arguments of generic types are dropped from the name, and types of
+	// the same name from different packages are numbered.
+	base := t.Name()
+	if i := strings.IndexByte(base, '['); i >= 0 {
+		base = base[:i]
+	}
+	name := base
+	for i := 2; g.taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.names[t] = name
+	g.taken[name] = true
+
+	// Reserve the position of the declaration before the fields are walked,
+	// which may declare other types or refer to t itself.
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+	obj := g.object(t, false)
+	g.decls[i] = fmt.Sprintf("export interface %s %s\n", name, obj)
+	return name
+}
+
+// object returns the object type of the struct type t, on a single line if
+// inline is set.
+func (g *generator) object(t reflect.Type, inline bool) string {
+	fields := jsonFields(t)
+	if len(fields) == 0 {
+		return "{}"
+	}
+
+	members := make([]string, 0, len(fields))
+	for _, f := range fields {
+		typ := g.typeOf(f.typ)
+		if f.quoted {
+			typ = "string"
+		}
+		if f.optional {
+			// Omitted fields are undefined rather than null.
+			typ = strings.TrimSuffix(typ, " | null")
+			members = append(members, fmt.Sprintf("%s?: %s;", property(f.name), typ))
+		} else {
+			members = append(members, fmt.Sprintf("%s: %s;", property(f.name), typ))
+		}
+	}
+	if inline {
+		return "{ " + strings.Join(members, " ") + " }"
+	}
+	return "{\n\t" + strings.Join(members, "\n\t") + "\n}"
+}
+
+func (g *generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// field is a struct field as encoded by encoding/json.
+type field struct {
+	name     string
+	typ      reflect.Type
+	optional bool
+	quoted   bool
+	depth    int
+}
+
+// jsonFields returns the fields of the struct type t that encoding/json
+// encodes, promoting the fields of embedded structs. Like encoding/json, a
+// field hides the fields of the same name that are embedded deeper.
+func jsonFields(t reflect.Type) []field {
+	var all []field
+	collectFields(t, 0, map[reflect.Type]bool{}, &all)
+
+	var fields []field
+	index := make(map[string]int)
+	for _, f := range all {
+		if i, ok := index[f.name]; ok {
+			if f.depth < fields[i].depth {
+				fields[i] = f
+			}
+			continue
+		}
+		index[f.name] = len(fields)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func collectFields(t reflect.Type, depth int, visited map[reflect.Type]bool, fields *[]field) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if !sf.IsExported() && ft.Kind() != reflect.Struct {
+				continue
+			}
+			if name == "" && ft.Kind() == reflect.Struct {
+				collectFields(ft, depth+1, visited, fields)
+				continue
+			}
+		} else if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		f := field{name: name, typ: sf.Type, depth: depth}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.optional = true
+			case "string":
+				switch sf.Type.Kind() {
+				case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+					reflect.Float32, reflect.Float64, reflect.String:
+					f.quoted = true
+				}
+			}
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// nullable returns typ or null.
+func nullable(typ string) string {
+	if strings.HasSuffix(typ, " | null") {
+		return typ
+	}
+	return typ + " | null"
+}
+
+func arrayOf(elem string) string {
+	if strings.Contains(elem, " | ") {
+		return "(" + elem + ")[]"
+	}
+	return elem + "[]"
+}
+
+// property returns name as a TypeScript property name, quoted if it isn't an
+// identifier.
+func property(name string) string {
+	if identifier.MatchString(name) {
+		return name
+	}
+	quoted, _ := json.Marshal(name)
+	return string(quoted)
+}
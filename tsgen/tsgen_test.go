@@ -0,0 +1,121 @@
+package tsgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+type User struct {
+	ID      int64     `json:"id,string"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email,omitempty"`
+	Tags    []string  `json:"tags"`
+	Avatar  []byte    `json:"avatar,omitempty"`
+	Friends []*User   `json:"friends"`
+	Created time.Time `json:"created"`
+	Address
+	secret  string
+	Ignored string `json:"-"`
+}
+
+type Address struct {
+	City    string            `json:"city"`
+	Country Country           `json:"country"`
+	Extra   map[string]string `json:"extra"`
+}
+
+// Country is marshaled as text.
+type Country string
+
+func (c Country) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+// Raw is marshaled by its own MarshalJSON.
+type Raw struct{}
+
+func (Raw) MarshalJSON() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+type Page[T any] struct {
+	Items []T  `json:"items"`
+	Next  *int `json:"next"`
+}
+
+type Users struct{}
+
+func (Users) Get(id int64) (*User, error)                            { return nil, nil }
+func (Users) List(ctx context.Context, page int) (Page[User], error) { return Page[User]{}, nil }
+func (Users) Delete(id int64) error                                  { return nil }
+func (Users) HTTPStatus() int                                        { return 0 }
+
+func bindings() map[string]interface{} {
+	users := Users{}
+	return map[string]interface{}{
+		"add":               func(a, b int) int { return a + b },
+		"sum":               func(values ...float64) float64 { return 0 },
+		"matrix":            func(m [][]int, fixed [2]bool) map[int][]string { return nil },
+		"raw":               func(r Raw, anything interface{}) (json.RawMessage, error) { return nil, nil },
+		"point":             func(p struct{ X, Y int }) *struct{ X, Y int } { return nil },
+		"notify":            func(ctx context.Context, message string) {},
+		"users.get":         users.Get,
+		"users.list":        users.List,
+		"users.delete":      users.Delete,
+		"users.httpStatus":  users.HTTPStatus,
+		"not-an-identifier": func() {},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Generate(&buf, bindings()); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "bindings.d.ts", buf.Bytes())
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := map[string]interface{}{
+		"is not a function":                        42,
+		"unsupported type chan int":                func(c chan int) {},
+		"unsupported map key type":                 func(m map[struct{}]int) {},
+		"may only return a value or a value+error": func() (int, int, error) { return 0, 0, nil },
+	}
+	for want, f := range tests {
+		err := Generate(&bytes.Buffer{}, map[string]interface{}{"f": f})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Generate(%T) = %v, want an error containing %q", f, err, want)
+		}
+	}
+}
+
+// golden compares got with the file name in testdata, or rewrites it with
+// -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, rerun with -update if the change is intended:\n%s", path, got)
+	}
+}
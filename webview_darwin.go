@@ -1,13 +1,17 @@
 package webview
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
 	"runtime"
 	"strings"
-	"sync"
 	"unsafe"
 
 	"github.com/ebitengine/purego/objc"
+	"github.com/mekkanized/go-webview/internal/bridge"
 	"github.com/mekkanized/go-webview/internal/darwin/cocoa"
 )
 
@@ -16,21 +20,35 @@ func init() {
 }
 
 type webview struct {
-	options  WebViewOptions
-	bindings map[string]interface{}
-	mutex    sync.RWMutex
+	options WebViewOptions
+	bridge  *bridge.Bridge
 
 	webview      cocoa.WKWebView
 	window       *cocoa.NSWindow
 	parentWindow *cocoa.NSWindow
 	manager      cocoa.WKUserContentController
+	// scripts holds the user scripts added with AddUserScript, in order.
+	scripts []cocoa.WKUserScript
+
+	// initErr is set when the webview failed to initialize after the
+	// application finished launching.
+	initErr error
+
+	destroyed bool
+}
+
+// NewApp creates an app that can host several windows.
+func NewApp(options AppOptions) (App, error) {
+	// TODO: Implement on top of the shared NSApplication
+	return nil, ErrNotSupported
 }
 
-func NewWithOptions(options WebViewOptions) WebView {
+// Create creates a new webview using the provided options.
+func Create(options WebViewOptions) (WebView, error) {
 	w := &webview{
-		bindings: make(map[string]interface{}),
-		options:  options,
+		options: options,
 	}
+	w.bridge = bridge.New(w)
 	if options.Window != nil {
 		w.parentWindow = &cocoa.NSWindow{ID: objc.ID(options.Window)}
 	}
@@ -38,7 +56,7 @@ func NewWithOptions(options WebViewOptions) WebView {
 	app := cocoa.NSApplication_GetSharedApplication()
 	delegate, err := w.createAppDelegate()
 	if err != nil {
-		panic(fmt.Errorf("failed to create app delegate: %w", err))
+		return nil, fmt.Errorf("failed to create app delegate: %w", err)
 	}
 	app.SetDelegate(delegate.ID)
 	// TODO: Set associated object
@@ -47,8 +65,11 @@ func NewWithOptions(options WebViewOptions) WebView {
 	} else {
 		app.Run()
 	}
+	if w.initErr != nil {
+		return nil, w.initErr
+	}
 
-	return w
+	return w, nil
 }
 
 func (w *webview) Run() {
@@ -65,7 +86,20 @@ func (w *webview) Dispatch(f func()) {
 }
 
 func (w *webview) Destroy() {
-	// TODO: Implement
+	if w.destroyed {
+		return
+	}
+	w.destroyed = true
+	w.bridge.Close()
+
+	w.manager.RemoveAllUserScripts()
+	w.scripts = nil
+	w.manager.RemoveScriptMessageHandlerForName("external")
+	if w.parentWindow == nil {
+		w.window.Close()
+	} else {
+		w.webview.RemoveFromSuperview()
+	}
 }
 
 func (w *webview) Window() unsafe.Pointer {
@@ -104,6 +138,81 @@ func (w *webview) SetSize(width int, height int, hint Hint) {
 	w.window.Center()
 }
 
+// SetSizeConstraints applies c with the content size limits of the window.
+// AppKit only supports a fixed aspect ratio, so MinAspect is used if both
+// bounds are set, and it cannot be combined with resize increments, which
+// are ignored if an aspect ratio is set.
+func (w *webview) SetSizeConstraints(c SizeConstraints) {
+	w.window.SetStyleMask(cocoa.NSWindowStyleMaskTitled | cocoa.NSWindowStyleMaskClosable | cocoa.NSWindowStyleMaskMiniaturizable | cocoa.NSWindowStyleMaskResizable)
+
+	w.window.SetContentMinSize(cocoa.NSSize{Width: float64(c.MinWidth), Height: float64(c.MinHeight)})
+
+	maxSize := cocoa.NSSize{Width: math.MaxFloat32, Height: math.MaxFloat32}
+	if c.MaxWidth > 0 {
+		maxSize.Width = float64(c.MaxWidth)
+	}
+	if c.MaxHeight > 0 {
+		maxSize.Height = float64(c.MaxHeight)
+	}
+	w.window.SetContentMaxSize(maxSize)
+
+	aspect := c.MinAspect
+	if aspect == 0 {
+		aspect = c.MaxAspect
+	}
+	if aspect > 0 {
+		w.window.SetContentAspectRatio(cocoa.NSSize{Width: aspect, Height: 1})
+		return
+	}
+
+	increments := cocoa.NSSize{Width: 1, Height: 1}
+	if c.WidthInc > 0 {
+		increments.Width = float64(c.WidthInc)
+	}
+	if c.HeightInc > 0 {
+		increments.Height = float64(c.HeightInc)
+	}
+	w.window.SetContentResizeIncrements(increments)
+}
+
+func (w *webview) Size() (width int, height int) {
+	// TODO: Implement using contentRectForFrameRect:
+	return 0, 0
+}
+
+func (w *webview) SetPosition(x int, y int) {
+	// TODO: Implement using setFrameTopLeftPoint:
+}
+
+func (w *webview) Position() (x int, y int) {
+	// TODO: Implement using frame
+	return 0, 0
+}
+
+func (w *webview) Maximize() {
+	// TODO: Implement using zoom:
+}
+
+func (w *webview) Unmaximize() {
+	// TODO: Implement using zoom:
+}
+
+func (w *webview) Minimize() {
+	// TODO: Implement using miniaturize:
+}
+
+func (w *webview) SetFullscreen(fullscreen bool) {
+	// TODO: Implement using toggleFullScreen:
+}
+
+func (w *webview) SetAlwaysOnTop(onTop bool) {
+	// TODO: Implement using setLevel:
+}
+
+func (w *webview) SetDecorated(decorated bool) {
+	// TODO: Implement using setStyleMask:
+}
+
 func (w *webview) Navigate(url string) {
 	pool := cocoa.NSAutoreleasePool_new()
 	defer pool.Release()
@@ -122,19 +231,114 @@ func (w *webview) SetHtml(html string) {
 }
 
 func (w *webview) Init(js string) {
+	w.AddUserScript(UserScriptOptions{Source: js})
+}
+
+// AddScript implements bridge.Host.
+func (w *webview) AddScript(js string) (remove func()) {
+	return w.AddUserScript(UserScriptOptions{Source: js}).Remove
+}
+
+// userScript is a script added to the user content controller of w.
+type userScript struct {
+	w      *webview
+	script cocoa.WKUserScript
+}
+
+func (s *userScript) Remove() {
+	s.w.removeScript(s.script)
+}
+
+func (w *webview) AddUserScript(options UserScriptOptions) UserScript {
+	injectionTime := cocoa.WKUserScriptInjectionTimeAtDocumentStart
+	if options.InjectionTime == UserScriptAtDocumentEnd {
+		injectionTime = cocoa.WKUserScriptInjectionTimeAtDocumentEnd
+	}
+
+	// TODO: Implement AllowList and BlockList by checking location.href in
+	// the script, WKUserScript has no URL patterns
 	script := cocoa.WKUserScript_alloc().
 		InitWithSource(
-			js,
-			cocoa.WKUserScriptInjectionTimeAtDocumentStart,
-			true,
+			options.Source,
+			injectionTime,
+			!options.AllFrames,
 		)
 	w.manager.AddUserScript(script)
+	w.scripts = append(w.scripts, script)
+
+	return &userScript{w: w, script: script}
+}
+
+// removeScript stops injecting script into new pages.
+// WKUserContentController can only remove all scripts, so the others are
+// added again.
+func (w *webview) removeScript(script cocoa.WKUserScript) {
+	for i, s := range w.scripts {
+		if s != script {
+			continue
+		}
+		w.scripts = append(w.scripts[:i], w.scripts[i+1:]...)
+
+		w.manager.RemoveAllUserScripts()
+		for _, s := range w.scripts {
+			w.manager.AddUserScript(s)
+		}
+		return
+	}
 }
 
 func (w *webview) Eval(js string) {
+	if w.destroyed {
+		return
+	}
 	w.webview.EvaluateJavaScript(js, objc.ID(0))
 }
 
+func (w *webview) EvalResult(ctx context.Context, js string) (json.RawMessage, error) {
+	// TODO: Implement using a completion handler block
+	return nil, ErrNotSupported
+}
+
+func (w *webview) OnNavigation(f func(e *NavigationEvent)) (off func()) {
+	// TODO: Implement using WKNavigationDelegate
+	return func() {}
+}
+
+func (w *webview) Cookies() CookieManager {
+	// TODO: Implement using WKHTTPCookieStore
+	return unsupportedCookieManager{}
+}
+
+func (w *webview) OnWindowEvent(f func(e *WindowEvent)) (off func()) {
+	// TODO: Implement using NSWindowDelegate
+	return func() {}
+}
+
+func (w *webview) SetNavigationPolicy(f func(d *PolicyDecision) Policy) {
+	// TODO: Implement using WKNavigationDelegate
+}
+
+func (w *webview) RegisterScheme(scheme string, handler http.Handler) error {
+	// TODO: Implement using WKURLSchemeHandler
+	return ErrNotSupported
+}
+
+// applyWindowOptions configures a window created by the webview.
+func (w *webview) applyWindowOptions(options WindowOptions) {
+	if options.Title != "" {
+		w.window.SetTitle(options.Title)
+	}
+	if options.Width > 0 && options.Height > 0 {
+		w.window.SetFrame(cocoa.NSRect{
+			Size: cocoa.NSSize{Width: float64(options.Width), Height: float64(options.Height)},
+		}, true, false)
+	}
+	if options.Center {
+		w.window.Center()
+	}
+	// TODO: Set options.Icon using NSApplication setApplicationIconImage:
+}
+
 func (w *webview) onApplicationDidFinishLaunching(delegateID objc.ID, appID objc.ID) {
 	app := cocoa.NSApplication{ID: appID}
 	if w.parentWindow == nil {
@@ -167,11 +371,13 @@ func (w *webview) onApplicationDidFinishLaunching(delegateID objc.ID, appID objc
 			Size:   cocoa.NSSize{Width: 0, Height: 0},
 		}, cocoa.NSWindowStyleMaskTitled, cocoa.NSBackingStoreBuffered, false)
 		w.window = &window
+		w.applyWindowOptions(w.options.WindowOptions)
 	} else {
 		w.window = w.parentWindow
 	}
 
 	config := cocoa.WKWebViewConfiguration_new()
+	// TODO: Honor Ephemeral using WKWebsiteDataStore nonPersistentDataStore
 	w.manager = config.UserContentController()
 	w.webview = cocoa.WKWebView_alloc().InitWithFrame(
 		cocoa.NSRect{
@@ -184,10 +390,19 @@ func (w *webview) onApplicationDidFinishLaunching(delegateID objc.ID, appID objc
 		config.Preferences().SetValue("developerExtrasEnabled", cocoa.NSNumber_NumberWithBool(true).ID)
 	}
 
-	uiDelegate := w.createWebkitUIDelegate()
+	// TODO: Cancel pending calls with a WKNavigationDelegate
+	uiDelegate, err := w.createWebkitUIDelegate()
+	if err != nil {
+		w.initErr = err
+		return
+	}
 	w.webview.SetUIDelegate(uiDelegate)
 
-	scriptMessageHandler := w.createScriptMessageHandler()
+	scriptMessageHandler, err := w.createScriptMessageHandler()
+	if err != nil {
+		w.initErr = err
+		return
+	}
 	w.manager.AddScriptMessageHandler(scriptMessageHandler, "external")
 
 	w.Init(`
@@ -197,6 +412,7 @@ func (w *webview) onApplicationDidFinishLaunching(delegateID objc.ID, appID objc
 			}
 		}
 	`)
+	w.Init(bridge.EventScript)
 	w.window.SetContentView(w.webview.ID)
 	w.window.MakeKeyAndOrderFront(0)
 }
@@ -266,10 +482,7 @@ func (w *webview) createAppDelegate() (*cocoa.WebviewAppDelegate, error) {
 	return res, nil
 }
 
-func (w *webview) createWebkitUIDelegate() objc.ID {
-	var res objc.ID
-	var err error
-
+func (w *webview) createWebkitUIDelegate() (objc.ID, error) {
 	class, err := objc.RegisterClass(
 		"WebviewUIDelegate",
 		cocoa.Class_NSObject,
@@ -304,14 +517,13 @@ func (w *webview) createWebkitUIDelegate() objc.ID {
 			},
 		})
 	if err != nil {
-		panic(fmt.Errorf("failed to register webkit ui delegate class: %w", err))
+		return 0, fmt.Errorf("failed to register webkit ui delegate class: %w", err)
 	}
-	res = objc.ID(class).Send(cocoa.Sel_new)
 
-	return res
+	return objc.ID(class).Send(cocoa.Sel_new), nil
 }
 
-func (w *webview) createScriptMessageHandler() objc.ID {
+func (w *webview) createScriptMessageHandler() (objc.ID, error) {
 	class, err := objc.RegisterClass(
 		"WebviewWKScriptMessageHandler",
 		cocoa.Class_NSResponder,
@@ -325,14 +537,13 @@ func (w *webview) createScriptMessageHandler() objc.ID {
 				Fn: func(self objc.ID, cmd objc.SEL, _ objc.ID, msg objc.ID) {
 					body := msg.Send(objc.RegisterName("body"))
 					// str := body.Send(objc.RegisterName("UTF8String"))
-					w.onMessage(cocoa.NSString{ID: body}.String())
+					w.bridge.OnMessage(cocoa.NSString{ID: body}.String())
 				},
 			},
 		})
 	if err != nil {
-		panic(fmt.Errorf("failed to register webkit ui delegate class: %w", err))
+		return 0, fmt.Errorf("failed to register script message handler class: %w", err)
 	}
-	res := objc.ID(class).Send(cocoa.Sel_new)
 
-	return res
+	return objc.ID(class).Send(cocoa.Sel_new), nil
 }
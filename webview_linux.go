@@ -3,108 +3,289 @@
 package webview
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
 	"sync"
 	"unsafe"
 
+	"github.com/mekkanized/go-webview/internal/bridge"
 	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
 )
 
-var webkit webkitgtk.Context = nil
-
 type webview struct {
-	options  WebViewOptions
-	bindings map[string]interface{}
-	mutex    sync.RWMutex
+	options WebViewOptions
+	bridge  *bridge.Bridge
+	app     *app
+	webkit  webkitgtk.Context
 
 	webview webkitgtk.WebKitWebView
 	window  webkitgtk.GtkWindow
+	// session holds the website data of webview.
+	session webkitgtk.WebKitNetworkSession
+	// closed is set once the window has been destroyed, destroyed once
+	// Destroy has been called. Both are guarded by mutex.
+	closed    bool
+	destroyed bool
+	// signals holds the handlers connected with connect.
+	signals []signalConnection
+	// scripts holds the user scripts added with AddUserScript, in order.
+	scripts []webkitgtk.WebKitUserScript
+	// x, y, width and height are the geometry of the last configure event.
+	x, y, width, height int
+	// geometry and geometryHints hold the size constraints of the window.
+	geometry      webkitgtk.GdkGeometry
+	geometryHints webkitgtk.GdkWindowHints
+
+	mutex              sync.Mutex
+	navigationHandlers map[uint64]func(e *NavigationEvent)
+	nextHandler        uint64
+	navigationPolicy   func(d *PolicyDecision) Policy
+	windowHandlers     map[uint64]func(e *WindowEvent)
+	// schemes holds the handlers passed to RegisterScheme.
+	schemes map[string]http.Handler
 }
 
-// NewWithOptions creates a new webview using the provided options.
-func NewWithOptions(options WebViewOptions) WebView {
-	w := &webview{
-		bindings: make(map[string]interface{}),
-		options:  options,
-	}
-
-	if webkit == nil {
-		var err error
-		webkit, err = webkitgtk.NewDefaultContext()
+// Create creates a new webview using the provided options. The error wraps
+// ErrLibraryNotFound, ErrUnsupportedVersion or ErrDisplayUnavailable when
+// WebKitGTK can't be used.
+func Create(options WebViewOptions) (WebView, error) {
+	if defaultApp == nil {
+		a, err := loadApp(AppOptions{WebKitLibrary: options.WebKitLibrary})
 		if err != nil {
-			panic(fmt.Errorf("failed to create webkit context: %w", err))
+			return nil, err
 		}
-		if err = webkit.LoadFunctions(); err != nil {
-			panic(fmt.Errorf("failed to load webkit functions: %w", err))
-		}
-
-		fmt.Printf("WebKitGTK version %d.%d.%d\n", webkit.WebKitGetMajorVersion(), webkit.WebKitGetMinorVersion(), webkit.WebKitGetMicroVersion())
+		defaultApp = a
 	}
 
-	// Initialize GTK
-	if !webkit.GtkInitCheck() {
-		panic(fmt.Errorf("failed to initialize GTK"))
+	return defaultApp.NewWindow(options)
+}
+
+// newWebView creates a webview in a new window of app.
+func newWebView(app *app, options WebViewOptions) (*webview, error) {
+	w := &webview{
+		options: options,
+		app:     app,
+		webkit:  app.webkit,
+
+		navigationHandlers: make(map[uint64]func(e *NavigationEvent)),
+		windowHandlers:     make(map[uint64]func(e *WindowEvent)),
+		schemes:            make(map[string]http.Handler),
 	}
+	w.bridge = bridge.New(w)
 
 	w.window = webkitgtk.GtkWindow(options.Window)
 	if w.window == webkitgtk.GtkWindow(webkitgtk.NULLPTR) {
-		w.window = webkitgtk.GtkWindow(webkit.GtkWindowNew(webkitgtk.GTK_WINDOW_TOPLEVEL))
+		w.window = webkitgtk.GtkWindow(w.webkit.GtkWindowNew(webkitgtk.GTK_WINDOW_TOPLEVEL))
+		w.applyWindowOptions(options.WindowOptions)
 	}
 
-	webkit.GSignalConnectData(webkitgtk.GtkWidget(w.window), "destroy", func() {
-		w.Terminate()
-	}, webkitgtk.NULLPTR, nil, webkitgtk.G_CONNECT_DEFAULT)
+	w.connect(webkitgtk.GtkWidget(w.window), "destroy", func(widget webkitgtk.GtkWidget, arg uintptr) {
+		w.setClosed()
+		w.app.windowClosed(w)
+	})
+	w.connectWindowEvents()
 
 	// Initialize webview widget
-	w.webview = webkitgtk.WebKitWebView(webkit.WebKitWebViewNew())
-	manager := webkit.WebKitWebViewGetUserContentManager(w.webview)
+	w.webview = w.newWebKitWebView(options)
+	manager := w.webkit.WebKitWebViewGetUserContentManager(w.webview)
 
 	// Setup binding callbacks
-	webkit.GSignalConnectData(webkitgtk.GtkWidget(manager), "script-message-received::external", func(manager webkitgtk.WebKitUserContentManager, result webkitgtk.WebKitJavascriptResult, arg uintptr) {
-		s, err := getStringFromJsResult(result)
+	w.connect(webkitgtk.GtkWidget(manager), "script-message-received::external", func(manager webkitgtk.WebKitUserContentManager, result webkitgtk.WebKitJavascriptResult, arg uintptr) {
+		s, err := w.getStringFromJsResult(result)
 		if err != nil {
-			fmt.Printf("RPC call failed: %v\n", fmt.Errorf("failed to get string from js result: %w", err))
+			log.Printf("invalid RPC message: %v", err)
+			return
 		}
 
-		w.onMessage(s)
-	}, webkitgtk.NULLPTR, nil, webkitgtk.G_CONNECT_DEFAULT)
+		w.bridge.OnMessage(s)
+	})
 
-	webkit.WebKitUserContentManagerRegisterScriptMessageHandler(manager, "external")
+	w.webkit.WebKitUserContentManagerRegisterScriptMessageHandler(manager, "external")
+
+	w.connectNavigation()
+	w.connectPolicy()
 
 	w.Init("window.external={invoke:function(s){window.webkit.messageHandlers.external.postMessage(s);}}")
+	w.Init(bridge.EventScript)
 
-	webkit.GtkContainerAdd(webkitgtk.GtkContainer(w.window), webkitgtk.GtkWidget(w.webview))
-	webkit.GtkWidgetGrabFocus(webkitgtk.GtkWidget(w.webview))
+	w.webkit.GtkContainerAdd(webkitgtk.GtkContainer(w.window), webkitgtk.GtkWidget(w.webview))
+	w.webkit.GtkWidgetGrabFocus(webkitgtk.GtkWidget(w.webview))
 
-	settings := webkit.WebKitWebViewGetSettings(w.webview)
-	webkit.WebKitSettingsSetJavascriptCanAccessClipboard(settings, true)
+	settings := w.webkit.WebKitWebViewGetSettings(w.webview)
+	w.webkit.WebKitSettingsSetJavascriptCanAccessClipboard(settings, true)
 	if options.Debug {
-		webkit.WebKitSettingsSetEnableWriteConsoleMessagesToStdout(settings, true)
-		webkit.WebKitSettingsSetEnableDeveloperExtras(settings, true)
+		w.webkit.WebKitSettingsSetEnableWriteConsoleMessagesToStdout(settings, true)
+		w.webkit.WebKitSettingsSetEnableDeveloperExtras(settings, true)
+	}
+
+	w.webkit.GtkWidgetShowAll(webkitgtk.GtkWidget(w.window))
+
+	return w, nil
+}
+
+// newWebKitWebView creates the web view in the network session selected by
+// options.
+func (w *webview) newWebKitWebView(options WebViewOptions) webkitgtk.WebKitWebView {
+	switch {
+	case options.Ephemeral:
+		w.session = w.webkit.WebKitNetworkSessionNewEphemeral()
+	case options.DataPath != "":
+		w.session = w.webkit.WebKitNetworkSessionNew(options.DataPath, filepath.Join(options.DataPath, "cache"))
+		cookies := w.webkit.WebKitNetworkSessionGetCookieManager(w.session)
+		w.webkit.WebKitCookieManagerSetPersistentStorage(cookies, filepath.Join(options.DataPath, "cookies.sqlite"), webkitgtk.WEBKIT_COOKIE_PERSISTENT_STORAGE_SQLITE)
+	default:
+		w.session = w.webkit.WebKitNetworkSessionGetDefault()
+		return webkitgtk.WebKitWebView(w.webkit.WebKitWebViewNew())
+	}
+
+	// The web view keeps the session alive.
+	webview := webkitgtk.WebKitWebView(w.webkit.WebKitWebViewNewWithNetworkSession(w.session))
+	w.webkit.GObjectUnref(webkitgtk.GObject(w.session))
+	return webview
+}
+
+// applyWindowOptions configures a window created by the webview before it is
+// shown.
+func (w *webview) applyWindowOptions(options WindowOptions) {
+	if options.Title != "" {
+		w.webkit.GtkWindowSetTitle(w.window, options.Title)
+	}
+	if options.Width > 0 && options.Height > 0 {
+		w.webkit.GtkWindowSetDefaultSize(w.window, int(options.Width), int(options.Height))
+	}
+	if options.Center {
+		w.webkit.GtkWindowSetPosition(w.window, webkitgtk.GTK_WIN_POS_CENTER)
+	}
+	if len(options.Icon) > 0 {
+		// An icon that cannot be decoded is ignored, as documented.
+		if icon, err := w.webkit.GdkPixbufNewFromBytes(options.Icon); err == nil {
+			w.webkit.GtkWindowSetIcon(w.window, icon)
+			w.webkit.GObjectUnref(webkitgtk.GObject(icon))
+		}
+	}
+}
+
+// loadWebKit loads the WebKitGTK library and the Context for its ABI.
+func loadWebKit(library string) (webkitgtk.Context, error) {
+	lib, err := webkitgtk.OpenLibrary(library)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLibraryNotFound, err)
+	}
+
+	// WebKitGTK 6.0 is built against GTK 4, which needs its own backend.
+	var ctx webkitgtk.Context
+	switch lib.ABI {
+	case webkitgtk.ABI60:
+		ctx, err = webkitgtk.NewGtk4Context(lib)
+	default:
+		ctx, err = webkitgtk.NewGtk3Context(lib)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create webkit context: %v", ErrUnsupportedVersion, err)
+	}
+	if err = ctx.LoadFunctions(); err != nil {
+		return nil, fmt.Errorf("%w: failed to load webkit functions: %v", ErrUnsupportedVersion, err)
 	}
 
-	webkit.GtkWidgetShowAll(webkitgtk.GtkWidget(w.window))
+	// The RPC bridge reads script messages as JSCValues.
+	major, minor := ctx.WebKitGetMajorVersion(), ctx.WebKitGetMinorVersion()
+	if major < 2 || (major == 2 && minor < 22) {
+		return nil, fmt.Errorf("%w: WebKitGTK %d.%d.%d is older than 2.22", ErrUnsupportedVersion, major, minor, ctx.WebKitGetMicroVersion())
+	}
 
-	return w
+	return ctx, nil
 }
 
 func (w *webview) Run() {
-	webkit.GtkMain()
+	w.app.Run()
 }
 
 func (w *webview) Terminate() {
-	webkit.GtkMainQuit()
+	w.app.Quit()
 }
 
 func (w *webview) Dispatch(f func()) {
-	webkit.GIdleAddFull(webkitgtk.G_PRIORITY_HIGH_IDLE, func(userData uintptr) bool {
-		f()
-		return webkitgtk.G_SOURCE_REMOVE
-	}, webkitgtk.NULLPTR, nil)
+	w.app.Dispatch(func() {
+		// The webview may have been destroyed since f was posted.
+		if !w.isDestroyed() {
+			f()
+		}
+	})
+}
+
+// isDestroyed reports whether the web view has been released, by Destroy or
+// along with its window.
+func (w *webview) isDestroyed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.destroyed || w.closed
 }
 
+func (w *webview) setClosed() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+}
+
+// Destroy releases the bindings, scripts and signal handlers of the webview
+// and destroys its window. The window passed in WebViewOptions.Window is only
+// emptied. Destroy may be called more than once, and after the window has
+// been closed.
 func (w *webview) Destroy() {
-	webkit = nil
+	w.mutex.Lock()
+	if w.destroyed {
+		w.mutex.Unlock()
+		return
+	}
+	w.destroyed = true
+	closed := w.closed
+	w.mutex.Unlock()
+	w.bridge.Close()
+
+	for _, script := range w.scripts {
+		w.webkit.WebKitUserScriptUnref(script)
+	}
+	w.scripts = nil
+
+	// GTK has already released everything along with the widgets.
+	if closed {
+		return
+	}
+
+	manager := w.webkit.WebKitWebViewGetUserContentManager(w.webview)
+	w.webkit.WebKitUserContentManagerRemoveAllScripts(manager)
+	w.webkit.WebKitUserContentManagerUnregisterScriptMessageHandler(manager, "external")
+
+	for _, s := range w.signals {
+		w.webkit.GSignalHandlerDisconnect(s.instance, s.id)
+	}
+	w.signals = nil
+
+	if w.options.Window == nil {
+		w.webkit.GtkWindowDestroy(w.window)
+	} else {
+		w.webkit.GtkContainerRemove(webkitgtk.GtkContainer(w.window), webkitgtk.GtkWidget(w.webview))
+	}
+	w.setClosed()
+	w.app.windowClosed(w)
+}
+
+type signalConnection struct {
+	instance webkitgtk.GtkWidget
+	id       uint32
+}
+
+// connect connects handler to a signal of instance, see
+// webkitgtk.GCallback, so that Destroy can disconnect it again.
+func (w *webview) connect(instance webkitgtk.GtkWidget, detailedSignal string, handler webkitgtk.GCallback) {
+	id := w.webkit.GSignalConnectData(instance, detailedSignal, handler, webkitgtk.NULLPTR, nil, webkitgtk.G_CONNECT_DEFAULT)
+	w.signals = append(w.signals, signalConnection{instance: instance, id: id})
 }
 
 func (w *webview) Window() unsafe.Pointer {
@@ -112,63 +293,277 @@ func (w *webview) Window() unsafe.Pointer {
 }
 
 func (w *webview) SetTitle(title string) {
-	webkit.GtkWindowSetTitle(w.window, title)
+	w.webkit.GtkWindowSetTitle(w.window, title)
 }
 
 func (w *webview) SetSize(width int, height int, hint Hint) {
-	webkit.GtkWindowSetResizable(w.window, hint != HintFixed)
+	w.webkit.GtkWindowSetResizable(w.window, hint != HintFixed)
 	switch hint {
 	case HintNone:
-		webkit.GtkWindowResize(w.window, width, height)
+		w.webkit.GtkWindowResize(w.window, width, height)
 	case HintFixed:
-		webkit.GtkWidgetSetSizeRequest(webkitgtk.GtkWidget(w.window), width, height)
+		w.webkit.GtkWidgetSetSizeRequest(webkitgtk.GtkWidget(w.window), width, height)
+	case HintMax:
+		w.geometry.MaxWidth = int32(width)
+		w.geometry.MaxHeight = int32(height)
+		w.geometryHints |= webkitgtk.GDK_HINT_MAX_SIZE
+		w.applyGeometry()
 	default:
-		geometry := webkitgtk.GdkGeometry{}
-
-		var h webkitgtk.GdkWindowHints
-		switch hint {
-		default:
-			fallthrough
-		case HintMin:
-			h = webkitgtk.GDK_HINT_MIN_SIZE
-			geometry.MinHeight = int32(height)
-			geometry.MinWidth = int32(width)
-		case HintMax:
-			h = webkitgtk.GDK_HINT_MAX_SIZE
-			geometry.MaxHeight = int32(height)
-			geometry.MaxWidth = int32(width)
+		w.geometry.MinWidth = int32(width)
+		w.geometry.MinHeight = int32(height)
+		w.geometryHints |= webkitgtk.GDK_HINT_MIN_SIZE
+		w.applyGeometry()
+	}
+}
+
+func (w *webview) SetSizeConstraints(c SizeConstraints) {
+	w.geometry = webkitgtk.GdkGeometry{}
+	w.geometryHints = 0
+
+	if c.MinWidth > 0 || c.MinHeight > 0 {
+		w.geometry.MinWidth = int32(c.MinWidth)
+		w.geometry.MinHeight = int32(c.MinHeight)
+		w.geometryHints |= webkitgtk.GDK_HINT_MIN_SIZE
+	}
+	if c.MaxWidth > 0 || c.MaxHeight > 0 {
+		// GDK requires both bounds, so a missing one is set to G_MAXINT.
+		w.geometry.MaxWidth, w.geometry.MaxHeight = math.MaxInt32, math.MaxInt32
+		if c.MaxWidth > 0 {
+			w.geometry.MaxWidth = int32(c.MaxWidth)
+		}
+		if c.MaxHeight > 0 {
+			w.geometry.MaxHeight = int32(c.MaxHeight)
 		}
-		webkit.GtkWindowSetGeometryHints(w.window, webkitgtk.GtkWidget(webkitgtk.NULLPTR), geometry, h)
+		w.geometryHints |= webkitgtk.GDK_HINT_MAX_SIZE
 	}
+	if c.MinAspect > 0 || c.MaxAspect > 0 {
+		w.geometry.MinAspect, w.geometry.MaxAspect = c.MinAspect, c.MaxAspect
+		if c.MinAspect == 0 {
+			w.geometry.MinAspect = c.MaxAspect
+		}
+		if c.MaxAspect == 0 {
+			w.geometry.MaxAspect = c.MinAspect
+		}
+		w.geometryHints |= webkitgtk.GDK_HINT_ASPECT
+	}
+	if c.WidthInc > 0 || c.HeightInc > 0 {
+		w.geometry.WidthInc, w.geometry.HeightInc = 1, 1
+		if c.WidthInc > 0 {
+			w.geometry.WidthInc = int32(c.WidthInc)
+		}
+		if c.HeightInc > 0 {
+			w.geometry.HeightInc = int32(c.HeightInc)
+		}
+		w.geometryHints |= webkitgtk.GDK_HINT_RESIZE_INC
+	}
+
+	w.webkit.GtkWindowSetResizable(w.window, true)
+	w.applyGeometry()
+}
+
+// applyGeometry sets all constraints at once, since every call to
+// gtk_window_set_geometry_hints replaces the previous ones.
+func (w *webview) applyGeometry() {
+	w.webkit.GtkWindowSetGeometryHints(w.window, webkitgtk.GtkWidget(webkitgtk.NULLPTR), w.geometry, w.geometryHints)
+}
+
+func (w *webview) Size() (width int, height int) {
+	return w.webkit.GtkWindowGetSize(w.window)
+}
+
+func (w *webview) SetPosition(x int, y int) {
+	w.webkit.GtkWindowMove(w.window, x, y)
+}
+
+func (w *webview) Position() (x int, y int) {
+	return w.webkit.GtkWindowGetPosition(w.window)
+}
+
+func (w *webview) Maximize() {
+	w.webkit.GtkWindowMaximize(w.window)
+}
+
+func (w *webview) Unmaximize() {
+	w.webkit.GtkWindowUnmaximize(w.window)
+}
+
+func (w *webview) Minimize() {
+	w.webkit.GtkWindowIconify(w.window)
+}
+
+func (w *webview) SetFullscreen(fullscreen bool) {
+	if fullscreen {
+		w.webkit.GtkWindowFullscreen(w.window)
+	} else {
+		w.webkit.GtkWindowUnfullscreen(w.window)
+	}
+}
+
+func (w *webview) SetAlwaysOnTop(onTop bool) {
+	w.webkit.GtkWindowSetKeepAbove(w.window, onTop)
+}
+
+func (w *webview) SetDecorated(decorated bool) {
+	w.webkit.GtkWindowSetDecorated(w.window, decorated)
 }
 
 func (w *webview) Navigate(url string) {
-	webkit.WebKitWebViewLoadURI(w.webview, url)
+	w.webkit.WebKitWebViewLoadURI(w.webview, url)
 }
 
 func (w *webview) SetHtml(html string) {
-	webkit.WebKitWebViewLoadHTML(w.webview, html, "")
+	w.webkit.WebKitWebViewLoadHTML(w.webview, html, "")
 }
 
 func (w *webview) Init(js string) {
-	manager := webkit.WebKitWebViewGetUserContentManager(w.webview)
+	w.AddUserScript(UserScriptOptions{Source: js})
+}
 
-	script := webkit.WebKitUserScriptNew(js, webkitgtk.WEBKIT_USER_CONTENT_INJECT_TOP_FRAME, webkitgtk.WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_START, "", "")
-	webkit.WebKitUserContentManagerAddScript(manager, script)
+// AddScript implements bridge.Host.
+func (w *webview) AddScript(js string) (remove func()) {
+	return w.AddUserScript(UserScriptOptions{Source: js}).Remove
+}
+
+// userScript is a script added to the user content manager of w.
+type userScript struct {
+	w      *webview
+	script webkitgtk.WebKitUserScript
+}
+
+func (s *userScript) Remove() {
+	s.w.removeScript(s.script)
+}
+
+func (w *webview) AddUserScript(options UserScriptOptions) UserScript {
+	frames := webkitgtk.WEBKIT_USER_CONTENT_INJECT_TOP_FRAME
+	if options.AllFrames {
+		frames = webkitgtk.WEBKIT_USER_CONTENT_INJECT_ALL_FRAMES
+	}
+	injectionTime := webkitgtk.WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_START
+	if options.InjectionTime == UserScriptAtDocumentEnd {
+		injectionTime = webkitgtk.WEBKIT_USER_SCRIPT_INJECT_AT_DOCUMENT_END
+	}
+
+	manager := w.webkit.WebKitWebViewGetUserContentManager(w.webview)
+	script := w.webkit.WebKitUserScriptNew(options.Source, frames, injectionTime, options.AllowList, options.BlockList)
+	w.webkit.WebKitUserContentManagerAddScript(manager, script)
+	w.scripts = append(w.scripts, script)
+
+	return &userScript{w: w, script: script}
+}
+
+// removeScript stops injecting script into new pages. The other scripts are
+// added again, since webkit_user_content_manager_remove_script needs
+// WebKitGTK 2.32.
+func (w *webview) removeScript(script webkitgtk.WebKitUserScript) {
+	for i, s := range w.scripts {
+		if s != script {
+			continue
+		}
+		w.scripts = append(w.scripts[:i], w.scripts[i+1:]...)
+
+		manager := w.webkit.WebKitWebViewGetUserContentManager(w.webview)
+		w.webkit.WebKitUserContentManagerRemoveAllScripts(manager)
+		for _, s := range w.scripts {
+			w.webkit.WebKitUserContentManagerAddScript(manager, s)
+		}
+		w.webkit.WebKitUserScriptUnref(script)
+		return
+	}
 }
 
 func (w *webview) Eval(js string) {
-	webkit.WebKitWebViewRunJavascript(w.webview, js, webkitgtk.GCancellable(webkitgtk.NULLPTR), nil, webkitgtk.NULLPTR)
+	if w.isDestroyed() {
+		return
+	}
+	w.webkit.WebKitWebViewRunJavascript(w.webview, js, webkitgtk.GCancellable(webkitgtk.NULLPTR), nil, webkitgtk.NULLPTR)
+}
+
+func (w *webview) EvalResult(ctx context.Context, js string) (json.RawMessage, error) {
+	type evalResult struct {
+		value json.RawMessage
+		err   error
+	}
+	if w.isDestroyed() {
+		return nil, ErrDestroyed
+	}
+	done := make(chan evalResult, 1)
+
+	// GCancellable is thread-safe, so it can be cancelled directly from here.
+	// Both this function and the completion callback hold a reference.
+	cancellable := w.webkit.GCancellableNew()
+	defer w.webkit.GObjectUnref(webkitgtk.GObject(cancellable))
+	w.webkit.GObjectRef(webkitgtk.GObject(cancellable))
+	w.app.Dispatch(func() {
+		if w.isDestroyed() {
+			w.webkit.GObjectUnref(webkitgtk.GObject(cancellable))
+			done <- evalResult{err: ErrDestroyed}
+			return
+		}
+		w.webkit.WebKitWebViewRunJavascript(w.webview, js, cancellable, func(sourceObject webkitgtk.GObject, res webkitgtk.GAsyncResult, userData uintptr) {
+			defer w.webkit.GObjectUnref(webkitgtk.GObject(cancellable))
+
+			result, err := w.webkit.WebKitWebViewRunJavascriptFinish(w.webview, res)
+			if err != nil {
+				done <- evalResult{err: w.evalError(ctx, err)}
+				return
+			}
+			defer w.webkit.WebKitJavascriptResultUnref(result)
+
+			value := w.webkit.JsCValueToJSON(w.webkit.WebKitJavascriptResultGetJsValue(result), 0)
+			switch {
+			case value == "":
+				done <- evalResult{value: json.RawMessage("null")}
+			case !json.Valid([]byte(value)):
+				// Older JavaScriptCore versions can only convert to strings.
+				raw, err := json.Marshal(value)
+				done <- evalResult{value: raw, err: err}
+			default:
+				done <- evalResult{value: json.RawMessage(value)}
+			}
+		}, webkitgtk.NULLPTR)
+	})
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		w.webkit.GCancellableCancel(cancellable)
+		return nil, ctx.Err()
+	}
+}
+
+// evalError returns the error EvalResult reports for an evaluation that
+// failed with err. Only exceptions thrown by the script are JSErrors.
+func (w *webview) evalError(ctx context.Context, err error) error {
+	if w.isDestroyed() {
+		return ErrDestroyed
+	}
+
+	var gerr *webkitgtk.GError
+	if !errors.As(err, &gerr) {
+		return fmt.Errorf("failed to evaluate script: %w", err)
+	}
+	switch {
+	case gerr.Domain == webkitgtk.WEBKIT_JAVASCRIPT_ERROR && gerr.Code == webkitgtk.WEBKIT_JAVASCRIPT_ERROR_SCRIPT_FAILED:
+		return &JSError{Message: gerr.Message}
+	case gerr.Domain == webkitgtk.G_IO_ERROR && gerr.Code == webkitgtk.G_IO_ERROR_CANCELLED:
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return context.Canceled
+	}
+	return fmt.Errorf("failed to evaluate script: %w", err)
 }
 
-func getStringFromJsResult(r webkitgtk.WebKitJavascriptResult) (string, error) {
+func (w *webview) getStringFromJsResult(r webkitgtk.WebKitJavascriptResult) (string, error) {
 	var str string
 
-	if webkit.WebKitGetMajorVersion() >= 2 && webkit.WebKitGetMinorVersion() >= 22 {
-		value := webkit.WebKitJavascriptResultGetJsValue(webkitgtk.WebKitJavascriptResult(r))
-		str = webkit.JsCValueToString(value)
+	if w.webkit.WebKitGetMajorVersion() >= 2 && w.webkit.WebKitGetMinorVersion() >= 22 {
+		value := w.webkit.WebKitJavascriptResultGetJsValue(webkitgtk.WebKitJavascriptResult(r))
+		str = w.webkit.JsCValueToString(value)
 	} else {
-		return "", fmt.Errorf("unsupported webkit version: %d.%d.%d", webkit.WebKitGetMajorVersion(), webkit.WebKitGetMinorVersion(), webkit.WebKitGetMicroVersion())
+		return "", fmt.Errorf("unsupported webkit version: %d.%d.%d", w.webkit.WebKitGetMajorVersion(), w.webkit.WebKitGetMinorVersion(), w.webkit.WebKitGetMicroVersion())
 		// ctx := webkitloader.WebkitJavascriptResultGetGlobalContext(r)
 		// value := webkitloader.WebkitJavascriptResultGetValue(r)
 		// c_str := webkitloader.JSValueToStringCopy(ctx, value, nil)
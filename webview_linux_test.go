@@ -0,0 +1,746 @@
+//go:build linux
+
+package webview
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk/webkitgtktest"
+)
+
+// newTestWebView creates a webview of an app backed by a Recorder.
+func newTestWebView(t *testing.T) (*webview, *webkitgtktest.Recorder) {
+	t.Helper()
+
+	r := webkitgtktest.NewRecorder()
+	a, err := newApp(r, AppOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := a.newWindow(WebViewOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, r
+}
+
+// runMainLoop runs the main loop of the app of w until the test ends.
+func runMainLoop(t *testing.T, w *webview) {
+	done := make(chan struct{})
+	go func() {
+		w.app.Run()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		w.app.Dispatch(w.app.Quit)
+		<-done
+	})
+}
+
+// lastGeometry returns the arguments of the last GtkWindowSetGeometryHints
+// call.
+func lastGeometry(t *testing.T, r *webkitgtktest.Recorder) (webkitgtk.GdkGeometry, webkitgtk.GdkWindowHints) {
+	t.Helper()
+
+	calls := r.CallsTo("GtkWindowSetGeometryHints")
+	if len(calls) == 0 {
+		t.Fatal("GtkWindowSetGeometryHints was not called")
+	}
+	args := calls[len(calls)-1].Args
+	return args[2].(webkitgtk.GdkGeometry), args[3].(webkitgtk.GdkWindowHints)
+}
+
+func TestSizeHints(t *testing.T) {
+	w, r := newTestWebView(t)
+
+	w.SetSize(300, 200, HintMin)
+	w.SetSize(800, 600, HintMax)
+	geometry, hints := lastGeometry(t, r)
+	want := webkitgtk.GdkGeometry{MinWidth: 300, MinHeight: 200, MaxWidth: 800, MaxHeight: 600}
+	if geometry != want || hints != webkitgtk.GDK_HINT_MIN_SIZE|webkitgtk.GDK_HINT_MAX_SIZE {
+		t.Errorf("SetSize set %+v with hints %d, want %+v with the min and max hints", geometry, hints, want)
+	}
+
+	// SetSizeConstraints replaces the bounds of SetSize.
+	w.SetSizeConstraints(SizeConstraints{MaxWidth: 1024, MaxAspect: 2})
+	geometry, hints = lastGeometry(t, r)
+	want = webkitgtk.GdkGeometry{MaxWidth: 1024, MaxHeight: 1<<31 - 1, MinAspect: 2, MaxAspect: 2}
+	if geometry != want || hints != webkitgtk.GDK_HINT_MAX_SIZE|webkitgtk.GDK_HINT_ASPECT {
+		t.Errorf("SetSizeConstraints set %+v with hints %d, want %+v with the max and aspect hints", geometry, hints, want)
+	}
+
+	// SetSize adds to the constraints.
+	w.SetSize(100, 50, HintMin)
+	geometry, hints = lastGeometry(t, r)
+	want.MinWidth, want.MinHeight = 100, 50
+	if geometry != want || hints != webkitgtk.GDK_HINT_MIN_SIZE|webkitgtk.GDK_HINT_MAX_SIZE|webkitgtk.GDK_HINT_ASPECT {
+		t.Errorf("SetSize set %+v with hints %d, want %+v with all hints", geometry, hints, want)
+	}
+
+	w.SetSizeConstraints(SizeConstraints{})
+	if _, hints = lastGeometry(t, r); hints != 0 {
+		t.Errorf("empty constraints set hints %d", hints)
+	}
+}
+
+// recordWindowEvents collects the window events of w. Close requests are
+// cancelled while *veto is true.
+func recordWindowEvents(w *webview, veto *bool) *[]WindowEvent {
+	var events []WindowEvent
+	w.OnWindowEvent(func(e *WindowEvent) {
+		if e.Type == WindowCloseRequested {
+			e.Cancel = *veto
+		}
+		events = append(events, *e)
+	})
+	return &events
+}
+
+func TestWindowEvents(t *testing.T) {
+	w, r := newTestWebView(t)
+	defer w.Destroy()
+	window := uintptr(w.window)
+
+	veto := true
+	events := recordWindowEvents(w, &veto)
+	if ret := r.Emit(window, "delete-event", r.NewEvent(nil)); ret != 1 {
+		t.Errorf("delete-event returned %d with the close vetoed, want TRUE", ret)
+	}
+	veto = false
+	if ret := r.Emit(window, "delete-event", r.NewEvent(nil)); ret != 0 {
+		t.Errorf("delete-event returned %d, want FALSE", ret)
+	}
+
+	r.Emit(window, "configure-event", r.NewEvent(webkitgtk.GdkEventConfigure{X: 10, Y: 20, Width: 640, Height: 480}))
+	// Unchanged geometry is not reported again.
+	r.Emit(window, "configure-event", r.NewEvent(webkitgtk.GdkEventConfigure{X: 10, Y: 20, Width: 640, Height: 480}))
+	r.Emit(window, "configure-event", r.NewEvent(webkitgtk.GdkEventConfigure{X: 10, Y: 20, Width: 800, Height: 600}))
+	r.Emit(window, "focus-in-event", r.NewEvent(nil))
+	r.Emit(window, "focus-out-event", r.NewEvent(nil))
+	r.Emit(window, "window-state-event", r.NewEvent(webkitgtk.GdkEventWindowState{
+		ChangedMask:    webkitgtk.GDK_WINDOW_STATE_MAXIMIZED,
+		NewWindowState: webkitgtk.GDK_WINDOW_STATE_MAXIMIZED | webkitgtk.GDK_WINDOW_STATE_FULLSCREEN | webkitgtk.GDK_WINDOW_STATE_FOCUSED,
+	}))
+	// Changes of other states are not reported.
+	r.Emit(window, "window-state-event", r.NewEvent(webkitgtk.GdkEventWindowState{
+		ChangedMask:    webkitgtk.GDK_WINDOW_STATE_FOCUSED,
+		NewWindowState: webkitgtk.GDK_WINDOW_STATE_MAXIMIZED,
+	}))
+	r.Emit(window, "window-state-event", r.NewEvent(webkitgtk.GdkEventWindowState{
+		ChangedMask:    webkitgtk.GDK_WINDOW_STATE_MAXIMIZED | webkitgtk.GDK_WINDOW_STATE_ICONIFIED,
+		NewWindowState: webkitgtk.GDK_WINDOW_STATE_ICONIFIED,
+	}))
+
+	want := []WindowEvent{
+		{Type: WindowCloseRequested, Cancel: true},
+		{Type: WindowCloseRequested},
+		{Type: WindowMoved, X: 10, Y: 20, Width: 640, Height: 480},
+		{Type: WindowResized, X: 10, Y: 20, Width: 640, Height: 480},
+		{Type: WindowResized, X: 10, Y: 20, Width: 800, Height: 600},
+		{Type: WindowFocused},
+		{Type: WindowBlurred},
+		{Type: WindowStateChanged, State: WindowStateMaximized | WindowStateFullscreen},
+		{Type: WindowStateChanged, State: WindowStateMinimized},
+	}
+	if !reflect.DeepEqual(*events, want) {
+		t.Errorf("window events are\n%+v\nwant\n%+v", *events, want)
+	}
+}
+
+func TestWindowEventsGTK4(t *testing.T) {
+	r := webkitgtktest.NewRecorder()
+	r.ABI = webkitgtk.ABI60
+	a, err := newApp(r, AppOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := a.newWindow(WebViewOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+	window := uintptr(w.window)
+
+	veto := true
+	events := recordWindowEvents(w, &veto)
+	if ret := r.Emit(window, "close-request"); ret != 1 {
+		t.Errorf("close-request returned %d with the close vetoed, want TRUE", ret)
+	}
+	veto = false
+	if ret := r.Emit(window, "close-request"); ret != 0 {
+		t.Errorf("close-request returned %d, want FALSE", ret)
+	}
+
+	r.GtkWindowResize(w.window, 640, 480)
+	r.Emit(window, "notify::default-width", 0)
+	// The height notification follows with the same size.
+	r.Emit(window, "notify::default-height", 0)
+	r.SetWindowState(w.window, webkitgtk.GDK_WINDOW_STATE_FOCUSED)
+	r.Emit(window, "notify::is-active", 0)
+	r.SetWindowState(w.window, webkitgtk.GDK_WINDOW_STATE_MAXIMIZED)
+	r.Emit(window, "notify::is-active", 0)
+	r.Emit(window, "notify::maximized", 0)
+	r.SetWindowState(w.window, webkitgtk.GDK_WINDOW_STATE_MAXIMIZED|webkitgtk.GDK_WINDOW_STATE_FULLSCREEN)
+	r.Emit(window, "notify::fullscreened", 0)
+
+	want := []WindowEvent{
+		{Type: WindowCloseRequested, Cancel: true},
+		{Type: WindowCloseRequested},
+		{Type: WindowResized, Width: 640, Height: 480},
+		{Type: WindowFocused},
+		{Type: WindowBlurred},
+		{Type: WindowStateChanged, State: WindowStateMaximized},
+		{Type: WindowStateChanged, State: WindowStateMaximized | WindowStateFullscreen},
+	}
+	if !reflect.DeepEqual(*events, want) {
+		t.Errorf("window events are\n%+v\nwant\n%+v", *events, want)
+	}
+}
+
+func TestRemoveScript(t *testing.T) {
+	w, r := newTestWebView(t)
+
+	var scripts []*userScript
+	for _, source := range []string{"a", "b", "c"} {
+		scripts = append(scripts, w.AddUserScript(UserScriptOptions{Source: source}).(*userScript))
+	}
+	added := len(r.CallsTo("WebKitUserContentManagerAddScript"))
+
+	scripts[1].Remove()
+	if n := len(r.CallsTo("WebKitUserContentManagerRemoveAllScripts")); n != 1 {
+		t.Fatalf("WebKitUserContentManagerRemoveAllScripts was called %d times, want 1", n)
+	}
+	// The scripts of the bridge come first, they were added by newWebView.
+	readded := r.CallsTo("WebKitUserContentManagerAddScript")[added:]
+	if len(readded) != len(w.scripts) {
+		t.Fatalf("%d scripts were added again, want %d", len(readded), len(w.scripts))
+	}
+	for i, call := range readded {
+		if call.Args[1] != w.scripts[i] {
+			t.Errorf("script %d added again is %v, want %v", i, call.Args[1], w.scripts[i])
+		}
+	}
+	if got := w.scripts[len(w.scripts)-2:]; got[0] != scripts[0].script || got[1] != scripts[2].script {
+		t.Errorf("remaining scripts end with %v, want a and c", got)
+	}
+	unref := r.CallsTo("WebKitUserScriptUnref")
+	if len(unref) != 1 || unref[0].Args[0] != scripts[1].script {
+		t.Errorf("unreferenced %v, want b", unref)
+	}
+
+	// Removing twice does nothing.
+	scripts[1].Remove()
+	if n := len(r.CallsTo("WebKitUserContentManagerRemoveAllScripts")); n != 1 {
+		t.Errorf("removing a script twice removed all scripts %d times", n)
+	}
+}
+
+func TestSoupCookie(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		cookie *http.Cookie
+		data   webkitgtk.SoupCookieData
+		back   *http.Cookie
+	}{
+		{
+			cookie: &http.Cookie{Name: "session", Value: "1"},
+			data:   webkitgtk.SoupCookieData{Name: "session", Value: "1", Domain: "example.com", Path: "/", SameSite: webkitgtk.SOUP_SAME_SITE_POLICY_LAX},
+			back:   &http.Cookie{Name: "session", Value: "1", Domain: "example.com", Path: "/", SameSite: http.SameSiteLaxMode},
+		},
+		{
+			cookie: &http.Cookie{Name: "id", Value: "2", Domain: ".example.com", Path: "/app", Expires: expires, Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+			data:   webkitgtk.SoupCookieData{Name: "id", Value: "2", Domain: ".example.com", Path: "/app", Expires: expires.Unix(), Secure: true, HTTPOnly: true, SameSite: webkitgtk.SOUP_SAME_SITE_POLICY_STRICT},
+			back:   &http.Cookie{Name: "id", Value: "2", Domain: "example.com", Path: "/app", Expires: time.Unix(expires.Unix(), 0), Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+		},
+		{
+			cookie: &http.Cookie{Name: "gone", Domain: "example.com", MaxAge: -1, SameSite: http.SameSiteNoneMode},
+			data:   webkitgtk.SoupCookieData{Name: "gone", Domain: ".example.com", Path: "/", Expires: 1, SameSite: webkitgtk.SOUP_SAME_SITE_POLICY_NONE},
+			back:   &http.Cookie{Name: "gone", Domain: "example.com", Path: "/", Expires: time.Unix(1, 0), SameSite: http.SameSiteNoneMode},
+		},
+	}
+	for _, tt := range tests {
+		data, err := toSoupCookie("https://example.com/app/index.html", tt.cookie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data != tt.data {
+			t.Errorf("toSoupCookie(%v) = %+v, want %+v", tt.cookie, data, tt.data)
+		}
+		if back := fromSoupCookie(data); !reflect.DeepEqual(back, tt.back) {
+			t.Errorf("fromSoupCookie(%+v) = %#v, want %#v", data, back, tt.back)
+		}
+	}
+}
+
+func TestCookies(t *testing.T) {
+	w, _ := newTestWebView(t)
+	runMainLoop(t, w)
+
+	ctx := context.Background()
+	cookies := w.Cookies()
+	if err := cookies.SetCookie(ctx, "https://example.com/", &http.Cookie{Name: "a", Value: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cookies.Cookies(ctx, "https://example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "a" || got[0].Value != "1" {
+		t.Errorf("Cookies returned %v, want a=1", got)
+	}
+
+	w.Destroy()
+	if _, err := cookies.Cookies(ctx, "https://example.com/"); !errors.Is(err, ErrDestroyed) {
+		t.Errorf("Cookies after Destroy returned %v, want ErrDestroyed", err)
+	}
+}
+
+func TestEvalResultDestroyed(t *testing.T) {
+	w, r := newTestWebView(t)
+	runMainLoop(t, w)
+	r.EvalFunc = func(script string) (string, error) { return "42", nil }
+
+	res, err := w.EvalResult(context.Background(), "6 * 7")
+	if err != nil || string(res) != "42" {
+		t.Errorf("EvalResult = %s, %v, want 42", res, err)
+	}
+
+	w.Destroy()
+	evals := len(r.CallsTo("WebKitWebViewRunJavascript"))
+	if _, err := w.EvalResult(context.Background(), "1"); err != ErrDestroyed {
+		t.Errorf("EvalResult after Destroy returned %v, want ErrDestroyed", err)
+	}
+	w.Eval("1")
+	w.Emit("event", nil)
+	if n := len(r.CallsTo("WebKitWebViewRunJavascript")); n != evals {
+		t.Errorf("%d scripts were run after Destroy", n-evals)
+	}
+}
+
+func TestEvalResultErrors(t *testing.T) {
+	w, r := newTestWebView(t)
+	runMainLoop(t, w)
+
+	exception := &webkitgtk.GError{Domain: webkitgtk.WEBKIT_JAVASCRIPT_ERROR, Code: webkitgtk.WEBKIT_JAVASCRIPT_ERROR_SCRIPT_FAILED, Message: "ReferenceError: x is not defined"}
+	cancelled := &webkitgtk.GError{Domain: webkitgtk.G_IO_ERROR, Code: webkitgtk.G_IO_ERROR_CANCELLED, Message: "Operation was cancelled"}
+	invalid := &webkitgtk.GError{Domain: webkitgtk.WEBKIT_JAVASCRIPT_ERROR, Code: 601, Message: "Unsupported result type"}
+
+	var err error
+	r.EvalFunc = func(script string) (string, error) { return "", err }
+
+	err = exception
+	_, got := w.EvalResult(context.Background(), "x")
+	var jsErr *JSError
+	if !errors.As(got, &jsErr) || jsErr.Message != exception.Message {
+		t.Errorf("an exception was returned as %v, want a *JSError", got)
+	}
+
+	err = cancelled
+	if _, got = w.EvalResult(context.Background(), "x"); got != context.Canceled {
+		t.Errorf("a cancelled evaluation returned %v, want %v", got, context.Canceled)
+	}
+
+	err = invalid
+	_, got = w.EvalResult(context.Background(), "x")
+	if got == nil || errors.As(got, &jsErr) || !errors.Is(got, invalid) {
+		t.Errorf("a failed evaluation returned %#v, want the GError", got)
+	}
+}
+
+func TestNavigationPolicy(t *testing.T) {
+	w, r := newTestWebView(t)
+
+	var opened []string
+	defer func(f func(string) error) { openExternal = f }(openExternal)
+	openExternal = func(url string) error {
+		opened = append(opened, url)
+		return errors.New("no browser")
+	}
+
+	var decisions []PolicyDecision
+	w.SetNavigationPolicy(func(d *PolicyDecision) Policy {
+		decisions = append(decisions, *d)
+		switch d.URL {
+		case "https://example.com/external":
+			return PolicyOpenExternal
+		case "https://example.com/blocked":
+			return PolicyIgnore
+		}
+		return PolicyAllow
+	})
+
+	decide := func(decision webkitgtk.WebKitPolicyDecision, decisionType webkitgtk.WebKitPolicyDecisionType) bool {
+		return r.Emit(uintptr(w.webview), "decide-policy", uintptr(decision), uintptr(decisionType)) != 0
+	}
+	navigation := webkitgtk.WEBKIT_POLICY_DECISION_TYPE_NAVIGATION_ACTION
+	response := webkitgtk.WEBKIT_POLICY_DECISION_TYPE_RESPONSE
+
+	allowed := r.NewPolicyDecision("https://example.com/", true, "")
+	if !decide(allowed, navigation) || len(r.CallsTo("WebKitPolicyDecisionUse")) != 1 {
+		t.Error("an allowed navigation was not used")
+	}
+
+	// Allowed responses get the default handling of WebKit, which downloads
+	// what it can't display.
+	file := r.NewPolicyDecision("https://example.com/file.zip", false, "application/zip")
+	if decide(file, response) || len(r.CallsTo("WebKitPolicyDecisionUse")) != 1 {
+		t.Error("an allowed response was decided instead of left to WebKit")
+	}
+	if d := decisions[len(decisions)-1]; d.Type != DecisionResponse || d.MIMEType != "application/zip" || !d.MainFrame {
+		t.Errorf("got response decision %+v", d)
+	}
+	decide(r.NewSubframeDecision("https://ads.example/", false, "text/html"), response)
+	if d := decisions[len(decisions)-1]; d.MainFrame {
+		t.Errorf("a response of an iframe is reported as the main frame: %+v", d)
+	}
+
+	external := r.NewPolicyDecision("https://example.com/external", true, "")
+	if !decide(external, navigation) || len(opened) != 1 || opened[0] != "https://example.com/external" {
+		t.Errorf("PolicyOpenExternal opened %v", opened)
+	}
+	blocked := r.NewPolicyDecision("https://example.com/blocked", true, "")
+	decide(blocked, navigation)
+	ignored := r.CallsTo("WebKitPolicyDecisionIgnore")
+	if len(ignored) != 2 || ignored[0].Args[0] != external || ignored[1].Args[0] != blocked {
+		t.Errorf("ignored %v, want the external and blocked navigations", ignored)
+	}
+}
+
+func TestNavigationEvents(t *testing.T) {
+	w, r := newTestWebView(t)
+	defer w.Destroy()
+	webview := uintptr(w.webview)
+
+	var events []NavigationEvent
+	w.OnNavigation(func(e *NavigationEvent) {
+		events = append(events, *e)
+	})
+
+	r.StatusCode = 200
+	r.WebKitWebViewLoadURI(w.webview, "https://example.com/")
+	for _, loadEvent := range []webkitgtk.WebKitLoadEvent{
+		webkitgtk.WEBKIT_LOAD_STARTED,
+		webkitgtk.WEBKIT_LOAD_REDIRECTED,
+		webkitgtk.WEBKIT_LOAD_COMMITTED,
+		webkitgtk.WEBKIT_LOAD_FINISHED,
+		// Unknown load events are not reported.
+		webkitgtk.WEBKIT_LOAD_FINISHED + 1,
+	} {
+		r.Emit(webview, "load-changed", uintptr(loadEvent))
+	}
+
+	want := []NavigationEvent{
+		{Type: NavigationStarted, URL: "https://example.com/"},
+		{Type: NavigationRedirected, URL: "https://example.com/"},
+		{Type: NavigationCommitted, URL: "https://example.com/", StatusCode: 200},
+		{Type: NavigationFinished, URL: "https://example.com/", StatusCode: 200},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("navigation events are\n%+v\nwant\n%+v", events, want)
+	}
+}
+
+func TestNavigationCancelsCalls(t *testing.T) {
+	w, r := newTestWebView(t)
+	defer w.Destroy()
+
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	if err := w.Bind("wait", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r.Emit(uintptr(w.webview), "script-message-received::external", uintptr(r.ScriptMessage(`{"jsonrpc":"2.0","id":1,"method":"wait"}`)))
+	<-started
+
+	r.Emit(uintptr(w.webview), "load-changed", uintptr(webkitgtk.WEBKIT_LOAD_STARTED))
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("the context of the call ended with %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call was not cancelled when the page navigated away")
+	}
+}
+
+func TestNavigationFailed(t *testing.T) {
+	w, r := newTestWebView(t)
+	defer w.Destroy()
+	webview := uintptr(w.webview)
+
+	var events []NavigationEvent
+	w.OnNavigation(func(e *NavigationEvent) {
+		if e.URL == "https://example.com/custom" {
+			e.ErrorHTML = "<h1>Offline</h1>"
+		}
+		events = append(events, *e)
+	})
+
+	for _, test := range []struct {
+		uri    string
+		domain string
+		code   int
+		want   uintptr
+	}{
+		// Cancelled loads and loads stopped by a policy decision didn't fail.
+		{"https://example.com/cancelled", webkitgtk.WEBKIT_NETWORK_ERROR, webkitgtk.WEBKIT_NETWORK_ERROR_CANCELLED, 0},
+		{"https://example.com/interrupted", webkitgtk.WEBKIT_POLICY_ERROR, webkitgtk.WEBKIT_POLICY_ERROR_FRAME_LOAD_INTERRUPTED_BY_POLICY_CHANGE, 0},
+		{"https://example.com/failed", webkitgtk.WEBKIT_NETWORK_ERROR, 399, 0},
+		// A custom error page replaces the one of WebKit.
+		{"https://example.com/custom", webkitgtk.WEBKIT_POLICY_ERROR, 101, 1},
+	} {
+		gerr := r.NewError(&webkitgtk.GError{Domain: test.domain, Code: test.code, Message: "failed"})
+		if ret := r.Emit(webview, "load-failed", uintptr(webkitgtk.WEBKIT_LOAD_STARTED), r.NewString(test.uri), gerr); ret != test.want {
+			t.Errorf("load-failed for %s returned %d, want %d", test.uri, ret, test.want)
+		}
+	}
+
+	want := []NavigationEvent{
+		{Type: NavigationFailed, URL: "https://example.com/failed", Err: &NavigationError{Domain: webkitgtk.WEBKIT_NETWORK_ERROR, Code: 399, Message: "failed"}},
+		{Type: NavigationFailed, URL: "https://example.com/custom", Err: &NavigationError{Domain: webkitgtk.WEBKIT_POLICY_ERROR, Code: 101, Message: "failed"}, ErrorHTML: "<h1>Offline</h1>"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("navigation events are\n%+v\nwant\n%+v", events, want)
+	}
+	calls := r.CallsTo("WebKitWebViewLoadAlternateHTML")
+	if wantArgs := []interface{}{w.webview, "<h1>Offline</h1>", "https://example.com/custom", ""}; len(calls) != 1 || !reflect.DeepEqual(calls[0].Args, wantArgs) {
+		t.Errorf("WebKitWebViewLoadAlternateHTML calls are %v, want one with %v", calls, wantArgs)
+	}
+}
+
+// serveTestScheme makes a request for uri from w through the callback
+// registered for scheme and waits for it to be finished. It returns the call
+// finishing the request and the response body.
+func serveTestScheme(t *testing.T, r *webkitgtktest.Recorder, w *webview, scheme, uri string) (webkitgtktest.Call, string) {
+	t.Helper()
+
+	callback := r.SchemeCallback(r.WebKitWebViewGetContext(w.webview), scheme)
+	if callback == nil {
+		t.Fatalf("scheme %s is not registered", scheme)
+	}
+	request := r.NewSchemeRequest(w.webview, uri)
+	callback(request, 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.RunPending()
+		for _, call := range r.Calls() {
+			switch call.Name {
+			case "WebKitURISchemeRequestFinish", "WebKitURISchemeRequestFinishWithResponse", "WebKitURISchemeRequestFinishError":
+			default:
+				continue
+			}
+			if call.Args[0] != request {
+				continue
+			}
+			if call.Name == "WebKitURISchemeRequestFinishError" {
+				return call, ""
+			}
+			// The body is read from the last pipe handed to WebKit.
+			streams := r.CallsTo("GUnixInputStreamNew")
+			f := os.NewFile(uintptr(streams[len(streams)-1].Args[0].(int)), "response")
+			defer f.Close()
+			body, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return call, string(body)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("request for %s was not finished", uri)
+	return webkitgtktest.Call{}, ""
+}
+
+func TestSchemeDispatch(t *testing.T) {
+	// Both apps use the default web context.
+	r := webkitgtktest.NewRecorder()
+	var windows []*webview
+	for _, body := range []string{"one", "two"} {
+		a, err := newApp(r, AppOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := a.newWindow(WebViewOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Destroy()
+
+		body := body
+		if err := w.RegisterScheme("app", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, body)
+		})); err != nil {
+			t.Fatal(err)
+		}
+		windows = append(windows, w)
+	}
+	if calls := r.CallsTo("WebKitWebContextRegisterURIScheme"); len(calls) != 1 {
+		t.Fatalf("app was registered %d times on the shared context, want once", len(calls))
+	}
+
+	for i, want := range []string{"one", "two"} {
+		if call, body := serveTestScheme(t, r, windows[i], "app", "app://index.html"); call.Name != "WebKitURISchemeRequestFinishWithResponse" || body != want {
+			t.Errorf("request of window %d finished with %s and body %q, want %q", i, call.Name, body, want)
+		}
+	}
+
+	windows[0].Destroy()
+	if call, _ := serveTestScheme(t, r, windows[0], "app", "app://index.html"); call.Name != "WebKitURISchemeRequestFinishError" {
+		t.Errorf("request of a destroyed webview finished with %s, want an error", call.Name)
+	}
+	if err := windows[0].RegisterScheme("app", http.NotFoundHandler()); err != ErrDestroyed {
+		t.Errorf("RegisterScheme after Destroy returned %v, want ErrDestroyed", err)
+	}
+	if _, body := serveTestScheme(t, r, windows[1], "app", "app://index.html"); body != "two" {
+		t.Errorf("request of the remaining window returned %q, want %q", body, "two")
+	}
+
+	// The context is forgotten along with its last webview, so the scheme is
+	// registered again for the next one.
+	windows[1].Destroy()
+	w, err := windows[1].app.newWindow(WebViewOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+	if err := w.RegisterScheme("app", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		io.WriteString(rw, "three")
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if calls := r.CallsTo("WebKitWebContextRegisterURIScheme"); len(calls) != 2 {
+		t.Errorf("app was registered %d times, want twice", len(calls))
+	}
+	if _, body := serveTestScheme(t, r, w, "app", "app://index.html"); body != "three" {
+		t.Errorf("request of the new window returned %q, want %q", body, "three")
+	}
+}
+
+func TestSchemeContextFinalized(t *testing.T) {
+	r := webkitgtktest.NewRecorder()
+	a, err := newApp(r, AppOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := a.newWindow(WebViewOptions{Ephemeral: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+
+	if err := w.RegisterScheme("app", http.NotFoundHandler()); err != nil {
+		t.Fatal(err)
+	}
+	// A context allocated at the address of a finalized one needs the scheme
+	// registered again.
+	r.FinalizeContext(r.WebKitWebViewGetContext(w.webview))
+	if err := w.RegisterScheme("app", http.NotFoundHandler()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := r.CallsTo("WebKitWebContextRegisterURIScheme"); len(calls) != 2 {
+		t.Errorf("app was registered %d times, want twice", len(calls))
+	}
+}
+
+func TestSchemePanic(t *testing.T) {
+	w, r := newTestWebView(t)
+	defer w.Destroy()
+
+	if err := w.RegisterScheme("app", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})); err != nil {
+		t.Fatal(err)
+	}
+	call, _ := serveTestScheme(t, r, w, "app", "app://index.html")
+	if call.Name != "WebKitURISchemeRequestFinishError" || call.Args[1] != "scheme handler panicked: boom" {
+		t.Errorf("request of a panicking handler finished with %v, want the panic as error", call)
+	}
+}
+
+func TestSchemeResponse(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Header().Set("X-Test", "yes")
+		rw.WriteHeader(http.StatusNotFound)
+		io.WriteString(rw, "missing")
+	})
+
+	w, r := newTestWebView(t)
+	defer w.Destroy()
+	if err := w.RegisterScheme("app", handler); err != nil {
+		t.Fatal(err)
+	}
+	call, body := serveTestScheme(t, r, w, "app", "app://missing")
+	if call.Name != "WebKitURISchemeRequestFinishWithResponse" || body != "missing" {
+		t.Fatalf("request finished with %s and body %q, want a response with body %q", call.Name, body, "missing")
+	}
+	response := call.Args[1]
+	if got := r.CallsTo("WebKitURISchemeResponseSetStatus"); len(got) != 1 || !reflect.DeepEqual(got[0].Args, []interface{}{response, http.StatusNotFound, "Not Found"}) {
+		t.Errorf("status set with %v, want 404 Not Found", got)
+	}
+	var header []string
+	for _, c := range r.CallsTo("SoupMessageHeadersAppend") {
+		header = append(header, c.Args[1].(string)+": "+c.Args[2].(string))
+	}
+	sort.Strings(header)
+	if want := []string{"Content-Type: text/plain", "X-Test: yes"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("response headers are %q, want %q", header, want)
+	}
+
+	// WebKitGTK < 2.36 only takes the content type.
+	w, r = newTestWebView(t)
+	r.Minor = 34
+	defer w.Destroy()
+	if err := w.RegisterScheme("app", handler); err != nil {
+		t.Fatal(err)
+	}
+	call, body = serveTestScheme(t, r, w, "app", "app://missing")
+	if call.Name != "WebKitURISchemeRequestFinish" || call.Args[2] != int64(-1) || call.Args[3] != "text/plain" || body != "missing" {
+		t.Errorf("request finished with %v and body %q, want the stream of %q as text/plain", call, body, "missing")
+	}
+	if got := r.CallsTo("WebKitURISchemeResponseSetStatus"); len(got) != 0 {
+		t.Errorf("status set with %v before 2.36", got)
+	}
+}
+
+func TestSchemeContentType(t *testing.T) {
+	for _, test := range []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"", "<!DOCTYPE html><title>app</title>", "text/html; charset=utf-8"},
+		{"", "plain text", "text/plain; charset=utf-8"},
+		{"application/json", "{}", "application/json"},
+	} {
+		w, r := newTestWebView(t)
+		if err := w.RegisterScheme("app", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if test.contentType != "" {
+				rw.Header().Set("Content-Type", test.contentType)
+			}
+			io.WriteString(rw, test.body)
+		})); err != nil {
+			t.Fatal(err)
+		}
+		serveTestScheme(t, r, w, "app", "app://index")
+		if got := r.CallsTo("WebKitURISchemeResponseSetContentType"); len(got) != 1 || got[0].Args[1] != test.want {
+			t.Errorf("content type of %q set with %v, want %q", test.body, got, test.want)
+		}
+		w.Destroy()
+	}
+}
@@ -3,17 +3,22 @@
 package webview
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
 	"github.com/jchv/go-webview2"
 )
 
-// New calls NewWindow to create a new window and a new webview instance. If debug
-// is non-zero - developer tools will be enabled (if the platform supports them).
-func New(debug bool) WebView {
-	return webview2.New(debug)
+// NewApp creates an app that can host several windows.
+func NewApp(options AppOptions) (App, error) {
+	// TODO: Implement on top of the WebView2 message loop
+	return nil, ErrNotSupported
 }
 
-// NewWithOptions creates a new webview using the provided options.
-func NewWithOptions(options WebViewOptions) WebView {
+// Create creates a new webview using the provided options.
+func Create(options WebViewOptions) (WebView, error) {
 	winOptions := webview2.WebViewOptions{
 		Window:    options.Window,
 		Debug:     options.Debug,
@@ -27,5 +32,119 @@ func NewWithOptions(options WebViewOptions) WebView {
 			Center: options.WindowOptions.Center,
 		},
 	}
-	return webview2.NewWithOptions(winOptions)
+	w := webview2.NewWithOptions(winOptions)
+	if w == nil {
+		return nil, fmt.Errorf("%w: failed to create WebView2", ErrLibraryNotFound)
+	}
+	return &webview{WebView: w}, nil
+}
+
+// webview adapts webview2.WebView to WebView. The features go-webview2 has no
+// API for are stubbed.
+type webview struct {
+	webview2.WebView
+}
+
+func (w *webview) SetSizeConstraints(c SizeConstraints) {
+	// TODO: Implement by handling WM_GETMINMAXINFO
+}
+
+func (w *webview) Size() (width int, height int) {
+	// TODO: Implement using GetWindowRect
+	return 0, 0
+}
+
+func (w *webview) SetPosition(x int, y int) {
+	// TODO: Implement using SetWindowPos
+}
+
+func (w *webview) Position() (x int, y int) {
+	// TODO: Implement using GetWindowRect
+	return 0, 0
+}
+
+func (w *webview) Maximize() {
+	// TODO: Implement using ShowWindow
+}
+
+func (w *webview) Unmaximize() {
+	// TODO: Implement using ShowWindow
+}
+
+func (w *webview) Minimize() {
+	// TODO: Implement using ShowWindow
+}
+
+func (w *webview) SetFullscreen(fullscreen bool) {
+	// TODO: Implement using SetWindowLong and SetWindowPos
+}
+
+func (w *webview) SetAlwaysOnTop(onTop bool) {
+	// TODO: Implement using SetWindowPos with HWND_TOPMOST
+}
+
+func (w *webview) SetDecorated(decorated bool) {
+	// TODO: Implement using SetWindowLong
+}
+
+// AddUserScript injects the script with Init, ignoring the options, and it
+// can't be removed.
+func (w *webview) AddUserScript(options UserScriptOptions) UserScript {
+	// TODO: Implement using AddScriptToExecuteOnDocumentCreated
+	w.Init(options.Source)
+	return unremovableScript{}
+}
+
+// unremovableScript is the UserScript of a script that can't be removed.
+type unremovableScript struct{}
+
+func (unremovableScript) Remove() {}
+
+func (w *webview) EvalResult(ctx context.Context, js string) (json.RawMessage, error) {
+	// TODO: Implement using ExecuteScript
+	return nil, ErrNotSupported
+}
+
+func (w *webview) BindObject(namespace string, obj interface{}) error {
+	// TODO: Implement on top of the shared RPC bridge
+	return ErrNotSupported
+}
+
+func (w *webview) Unbind(name string) error {
+	// TODO: Implement on top of the shared RPC bridge
+	return ErrNotSupported
+}
+
+func (w *webview) Emit(event string, payload interface{}) error {
+	// TODO: Implement on top of the shared RPC bridge
+	return ErrNotSupported
+}
+
+func (w *webview) On(event string, f func(payload json.RawMessage)) (off func()) {
+	// TODO: Implement on top of the shared RPC bridge
+	return func() {}
+}
+
+func (w *webview) OnNavigation(f func(e *NavigationEvent)) (off func()) {
+	// TODO: Implement using NavigationStarting and NavigationCompleted
+	return func() {}
+}
+
+func (w *webview) Cookies() CookieManager {
+	// TODO: Implement using ICoreWebView2CookieManager
+	return unsupportedCookieManager{}
+}
+
+func (w *webview) OnWindowEvent(f func(e *WindowEvent)) (off func()) {
+	// TODO: Implement by subclassing the window procedure
+	return func() {}
+}
+
+func (w *webview) SetNavigationPolicy(f func(d *PolicyDecision) Policy) {
+	// TODO: Implement using NavigationStarting and NewWindowRequested
+}
+
+func (w *webview) RegisterScheme(scheme string, handler http.Handler) error {
+	// TODO: Implement using WebResourceRequested
+	return ErrNotSupported
 }
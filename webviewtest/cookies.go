@@ -0,0 +1,100 @@
+package webviewtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"github.com/mekkanized/go-webview"
+)
+
+// CookieManager is an in-memory webview.CookieManager backed by a
+// cookiejar.Jar. It is returned by WebView.Cookies.
+type CookieManager struct {
+	// StorageFile and Storage are set by SetPersistentStorage.
+	StorageFile string
+	Storage     webview.CookieStorage
+	// AcceptPolicy is set by SetAcceptPolicy. With webview.CookieAcceptNever
+	// SetCookie does not store anything.
+	AcceptPolicy webview.CookieAcceptPolicy
+
+	mutex sync.Mutex
+	jar   *cookiejar.Jar
+}
+
+var _ webview.CookieManager = (*CookieManager)(nil)
+
+func newCookieManager() *CookieManager {
+	jar, _ := cookiejar.New(nil)
+	return &CookieManager{jar: jar}
+}
+
+// Cookies returns the name and value of the cookies for url, as
+// http.CookieJar does.
+func (m *CookieManager) Cookies(ctx context.Context, rawURL string) ([]*http.Cookie, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.jar.Cookies(u), nil
+}
+
+func (m *CookieManager) SetCookie(ctx context.Context, rawURL string, cookie *http.Cookie) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.AcceptPolicy != webview.CookieAcceptNever {
+		m.jar.SetCookies(u, []*http.Cookie{cookie})
+	}
+	return nil
+}
+
+func (m *CookieManager) DeleteCookie(ctx context.Context, rawURL string, cookie *http.Cookie) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	deleted := *cookie
+	deleted.MaxAge = -1
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.jar.SetCookies(u, []*http.Cookie{&deleted})
+	return nil
+}
+
+func (m *CookieManager) Clear(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.jar, _ = cookiejar.New(nil)
+	return nil
+}
+
+func (m *CookieManager) SetPersistentStorage(filename string, storage webview.CookieStorage) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.StorageFile = filename
+	m.Storage = storage
+}
+
+func (m *CookieManager) SetAcceptPolicy(policy webview.CookieAcceptPolicy) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.AcceptPolicy = policy
+}
@@ -0,0 +1,554 @@
+// Package webviewtest provides an in-memory implementation of webview.WebView
+// for unit tests that must run without a display or native libraries.
+//
+// The fake records the calls made to it, and runs bindings and events through
+// the same RPC bridge as the real backends, so binding code can be tested by
+// simulating JavaScript with Invoke, Call and EmitJS.
+package webviewtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"unsafe"
+
+	"github.com/mekkanized/go-webview"
+	"github.com/mekkanized/go-webview/internal/bridge"
+)
+
+// ErrNavigated is returned by Call when the page navigates away before the
+// binding returns.
+var ErrNavigated = errors.New("webviewtest: page navigated away")
+
+// WebView is a fake webview.WebView. Its exported fields hold the state set
+// through the interface and must only be read while no other goroutine is
+// calling into the fake.
+type WebView struct {
+	Title  string
+	Width  int
+	Height int
+	Hint   webview.Hint
+	X      int
+	Y      int
+
+	// SizeConstraints is set by SetSizeConstraints, and its bounds by SetSize
+	// with webview.HintMin and webview.HintMax.
+	SizeConstraints webview.SizeConstraints
+
+	Maximized   bool
+	Minimized   bool
+	Fullscreen  bool
+	AlwaysOnTop bool
+	Decorated   bool
+
+	// URL and HTML are set by Navigate and SetHtml respectively.
+	URL  string
+	HTML string
+
+	// InitScripts and EvalScripts record the scripts passed to Init and Eval,
+	// including the ones the RPC bridge generates. Scripts are removed from
+	// InitScripts when they stop being injected into new pages.
+	InitScripts []string
+	EvalScripts []string
+
+	// UserScripts holds the scripts injected into new pages, including the
+	// ones added with Init, in the order of InitScripts.
+	UserScripts []*UserScript
+
+	// Schemes holds the handlers passed to RegisterScheme.
+	Schemes map[string]http.Handler
+
+	// EvalResultFunc is called by EvalResult. If nil, EvalResult returns null.
+	EvalResultFunc func(js string) (json.RawMessage, error)
+
+	Destroyed bool
+
+	mutex      sync.Mutex
+	bridge     *bridge.Bridge
+	cookies    *CookieManager
+	terminate  chan struct{}
+	terminated bool
+	nextCallID int
+	calls      map[int]chan callResult
+
+	navigationHandlers map[uint64]func(e *webview.NavigationEvent)
+	nextHandler        uint64
+	navigationPolicy   func(d *webview.PolicyDecision) webview.Policy
+	windowHandlers     map[uint64]func(e *webview.WindowEvent)
+}
+
+type callResult struct {
+	result json.RawMessage
+	err    error
+}
+
+var _ webview.WebView = (*WebView)(nil)
+
+// New creates a fake webview.
+func New() *WebView {
+	w := &WebView{
+		Decorated: true,
+		Schemes:   make(map[string]http.Handler),
+		cookies:   newCookieManager(),
+		terminate: make(chan struct{}),
+		calls:     make(map[int]chan callResult),
+
+		navigationHandlers: make(map[uint64]func(e *webview.NavigationEvent)),
+		windowHandlers:     make(map[uint64]func(e *webview.WindowEvent)),
+		// Call IDs are allocated from the top of the range to avoid colliding
+		// with the IDs of messages passed to Invoke.
+		nextCallID: 1 << 30,
+	}
+	w.bridge = bridge.New(w)
+	w.Init(bridge.EventScript)
+	return w
+}
+
+// Run blocks until Terminate is called.
+func (w *WebView) Run() {
+	<-w.terminate
+}
+
+func (w *WebView) Terminate() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.terminated {
+		w.terminated = true
+		close(w.terminate)
+	}
+}
+
+// Dispatch runs f immediately on the calling goroutine, unless the fake has
+// been destroyed.
+func (w *WebView) Dispatch(f func()) {
+	if w.isDestroyed() {
+		return
+	}
+	f()
+}
+
+func (w *WebView) isDestroyed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.Destroyed
+}
+
+// Destroy marks the fake as destroyed and releases its bindings and
+// listeners.
+func (w *WebView) Destroy() {
+	w.mutex.Lock()
+	w.Destroyed = true
+	w.mutex.Unlock()
+
+	w.bridge.Close()
+}
+
+// Window returns nil, since there is no native window.
+func (w *WebView) Window() unsafe.Pointer {
+	return nil
+}
+
+func (w *WebView) SetTitle(title string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Title = title
+}
+
+func (w *WebView) SetSize(width int, height int, hint webview.Hint) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Hint = hint
+	switch hint {
+	case webview.HintMin:
+		w.SizeConstraints.MinWidth = width
+		w.SizeConstraints.MinHeight = height
+	case webview.HintMax:
+		w.SizeConstraints.MaxWidth = width
+		w.SizeConstraints.MaxHeight = height
+	default:
+		w.Width = width
+		w.Height = height
+	}
+}
+
+func (w *WebView) SetSizeConstraints(c webview.SizeConstraints) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.SizeConstraints = c
+}
+
+func (w *WebView) Size() (width int, height int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.Width, w.Height
+}
+
+func (w *WebView) SetPosition(x int, y int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.X = x
+	w.Y = y
+}
+
+func (w *WebView) Position() (x int, y int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.X, w.Y
+}
+
+func (w *WebView) Maximize() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Maximized = true
+	w.Minimized = false
+}
+
+func (w *WebView) Unmaximize() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Maximized = false
+}
+
+func (w *WebView) Minimize() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Minimized = true
+}
+
+func (w *WebView) SetFullscreen(fullscreen bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Fullscreen = fullscreen
+}
+
+func (w *WebView) SetAlwaysOnTop(onTop bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.AlwaysOnTop = onTop
+}
+
+func (w *WebView) SetDecorated(decorated bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Decorated = decorated
+}
+
+func (w *WebView) Navigate(url string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.URL = url
+}
+
+func (w *WebView) SetHtml(html string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.HTML = html
+}
+
+func (w *WebView) Init(js string) {
+	w.AddUserScript(webview.UserScriptOptions{Source: js})
+}
+
+// AddScript implements bridge.Host.
+func (w *WebView) AddScript(js string) (remove func()) {
+	return w.AddUserScript(webview.UserScriptOptions{Source: js}).Remove
+}
+
+// UserScript is a script added with AddUserScript.
+type UserScript struct {
+	Options webview.UserScriptOptions
+
+	w *WebView
+}
+
+// Remove removes the script from UserScripts and InitScripts.
+func (s *UserScript) Remove() {
+	w := s.w
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for i, script := range w.UserScripts {
+		if script == s {
+			w.UserScripts = append(w.UserScripts[:i], w.UserScripts[i+1:]...)
+			w.InitScripts = append(w.InitScripts[:i], w.InitScripts[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *WebView) AddUserScript(options webview.UserScriptOptions) webview.UserScript {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	script := &UserScript{Options: options, w: w}
+	w.UserScripts = append(w.UserScripts, script)
+	w.InitScripts = append(w.InitScripts, options.Source)
+	return script
+}
+
+func (w *WebView) Eval(js string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.Destroyed {
+		return
+	}
+	w.EvalScripts = append(w.EvalScripts, js)
+}
+
+func (w *WebView) EvalResult(ctx context.Context, js string) (json.RawMessage, error) {
+	w.mutex.Lock()
+	if w.Destroyed {
+		w.mutex.Unlock()
+		return nil, webview.ErrDestroyed
+	}
+	w.EvalScripts = append(w.EvalScripts, js)
+	f := w.EvalResultFunc
+	w.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return json.RawMessage("null"), nil
+	}
+	return f(js)
+}
+
+func (w *WebView) Bind(name string, f interface{}) error {
+	return w.bridge.Bind(name, f)
+}
+
+func (w *WebView) BindObject(namespace string, obj interface{}) error {
+	return w.bridge.BindObject(namespace, obj)
+}
+
+func (w *WebView) Unbind(name string) error {
+	return w.bridge.Unbind(name)
+}
+
+func (w *WebView) Emit(event string, payload interface{}) error {
+	return w.bridge.Emit(event, payload)
+}
+
+func (w *WebView) On(event string, f func(payload json.RawMessage)) (off func()) {
+	return w.bridge.On(event, f)
+}
+
+func (w *WebView) OnNavigation(f func(e *webview.NavigationEvent)) (off func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.nextHandler++
+	id := w.nextHandler
+	w.navigationHandlers[id] = f
+
+	return func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.navigationHandlers, id)
+	}
+}
+
+// Cookies returns the *CookieManager of the fake.
+func (w *WebView) Cookies() webview.CookieManager {
+	return w.cookies
+}
+
+func (w *WebView) OnWindowEvent(f func(e *webview.WindowEvent)) (off func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.nextHandler++
+	id := w.nextHandler
+	w.windowHandlers[id] = f
+
+	return func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.windowHandlers, id)
+	}
+}
+
+func (w *WebView) SetNavigationPolicy(f func(d *webview.PolicyDecision) webview.Policy) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.navigationPolicy = f
+}
+
+func (w *WebView) RegisterScheme(scheme string, handler http.Handler) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.Schemes[scheme] = handler
+	return nil
+}
+
+// Bindings returns the bound functions by name.
+func (w *WebView) Bindings() map[string]interface{} {
+	return w.bridge.Bindings()
+}
+
+// Invoke delivers a raw JSON-RPC 2.0 message, as JavaScript would post it
+// with window.external.invoke.
+func (w *WebView) Invoke(msg string) {
+	w.bridge.OnMessage(msg)
+}
+
+// Call calls a binding with the given arguments, as JavaScript would call
+// window[method](params...), and waits for its result. Methods bound with
+// BindObject are called as "namespace.method", e.g. "api.getUser". Bindings
+// that take a context.Context receive one derived from ctx. Failed calls
+// return the error of the binding, or a *webview.RPCError for calls the
+// bridge rejects, and calls still running when the page navigates away
+// ErrNavigated.
+func (w *WebView) Call(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	serParams := make([]json.RawMessage, len(params))
+	for i, param := range params {
+		serParam, err := json.Marshal(param)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal argument %d: %w", i, err)
+		}
+		serParams[i] = serParam
+	}
+
+	w.mutex.Lock()
+	id := w.nextCallID
+	w.nextCallID++
+	done := make(chan callResult, 1)
+	w.calls[id] = done
+	w.mutex.Unlock()
+
+	msg, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  serParams,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.bridge.OnMessageContext(ctx, string(msg))
+
+	select {
+	case res := <-done:
+		return res.result, res.err
+	case <-ctx.Done():
+		w.mutex.Lock()
+		delete(w.calls, id)
+		w.mutex.Unlock()
+
+		w.Invoke(fmt.Sprintf(`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":%d}}`, id))
+		return nil, ctx.Err()
+	}
+}
+
+// EmitJS delivers an event to the subscribers registered with On, as
+// JavaScript would send it with window.webview.emit(event, payload).
+func (w *WebView) EmitJS(event string, payload interface{}) error {
+	msg, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "$/emit",
+		"params": map[string]interface{}{
+			"event":   event,
+			"payload": payload,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Invoke(string(msg))
+	return nil
+}
+
+// Navigation delivers e to the callbacks registered with OnNavigation, as
+// the native webview would while loading a page. The callbacks may set
+// e.ErrorHTML. A webview.NavigationStarted event cancels the contexts of the
+// running bindings, whose results are then dropped, and fails the calls
+// waiting for them with ErrNavigated.
+func (w *WebView) Navigation(e *webview.NavigationEvent) {
+	if e.Type == webview.NavigationStarted {
+		w.bridge.CancelPendingCalls()
+
+		w.mutex.Lock()
+		calls := w.calls
+		w.calls = make(map[int]chan callResult)
+		w.mutex.Unlock()
+		for _, done := range calls {
+			done <- callResult{err: ErrNavigated}
+		}
+	}
+
+	w.mutex.Lock()
+	handlers := make([]func(*webview.NavigationEvent), 0, len(w.navigationHandlers))
+	for _, f := range w.navigationHandlers {
+		handlers = append(handlers, f)
+	}
+	w.mutex.Unlock()
+
+	for _, f := range handlers {
+		f(e)
+	}
+}
+
+// WindowEvent delivers e to the callbacks registered with OnWindowEvent, as
+// the native window would. It returns false if a callback cancelled a
+// webview.WindowCloseRequested event.
+func (w *WebView) WindowEvent(e *webview.WindowEvent) bool {
+	w.mutex.Lock()
+	handlers := make([]func(*webview.WindowEvent), 0, len(w.windowHandlers))
+	for _, f := range w.windowHandlers {
+		handlers = append(handlers, f)
+	}
+	w.mutex.Unlock()
+
+	for _, f := range handlers {
+		f(e)
+	}
+	return !e.Cancel
+}
+
+// Decide returns the decision of the navigation policy for d, or
+// webview.PolicyAllow if none is set.
+func (w *WebView) Decide(d *webview.PolicyDecision) webview.Policy {
+	w.mutex.Lock()
+	policy := w.navigationPolicy
+	w.mutex.Unlock()
+
+	if policy == nil {
+		return webview.PolicyAllow
+	}
+	return policy(d)
+}
+
+// Result implements bridge.ResultHost to complete calls made with Call.
+func (w *WebView) Result(id int, result json.RawMessage, err error) {
+	w.mutex.Lock()
+	done, ok := w.calls[id]
+	delete(w.calls, id)
+	w.mutex.Unlock()
+
+	if ok {
+		done <- callResult{result: result, err: err}
+	}
+}
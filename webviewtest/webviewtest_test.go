@@ -0,0 +1,336 @@
+package webviewtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mekkanized/go-webview"
+	"github.com/mekkanized/go-webview/webviewtest"
+)
+
+func TestCall(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	if err := w.Bind("add", func(a, b int) int { return a + b }); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Bind("sum", func(values ...int) (int, error) {
+		total := 0
+		for _, v := range values {
+			total += v
+		}
+		return total, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := w.Call(context.Background(), "add", 1, 2)
+	if err != nil || string(res) != "3" {
+		t.Errorf("add(1, 2) = %s, %v, want 3", res, err)
+	}
+	res, err = w.Call(context.Background(), "sum", 1, 2, 3)
+	if err != nil || string(res) != "6" {
+		t.Errorf("sum(1, 2, 3) = %s, %v, want 6", res, err)
+	}
+	res, err = w.Call(context.Background(), "sum")
+	if err != nil || string(res) != "0" {
+		t.Errorf("sum() = %s, %v, want 0", res, err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	errFailed := errors.New("failed")
+	w.Bind("add", func(a, b int) int { return a + b })
+	w.Bind("fail", func() error { return errFailed })
+	w.Bind("reject", func() (int, error) {
+		return 0, &webview.RPCError{Code: 42, Message: "rejected", Data: "details"}
+	})
+
+	tests := []struct {
+		method string
+		params []interface{}
+		code   int
+	}{
+		{"missing", nil, webview.RPCMethodNotFound},
+		{"add", []interface{}{1}, webview.RPCInvalidParams},
+		{"add", []interface{}{1, "two"}, webview.RPCInvalidParams},
+		{"reject", nil, 42},
+	}
+	for _, tt := range tests {
+		_, err := w.Call(context.Background(), tt.method, tt.params...)
+		var rpcErr *webview.RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != tt.code {
+			t.Errorf("%s%v: got error %v, want code %d", tt.method, tt.params, err, tt.code)
+		}
+	}
+
+	if _, err := w.Call(context.Background(), "fail"); err != errFailed {
+		t.Errorf("fail(): got error %v, want %v", err, errFailed)
+	}
+	// Other errors reach JavaScript as internal errors.
+	if !strings.Contains(lastEval(w), `"error":{"code":-32603,"message":"failed"}`) {
+		t.Errorf("fail() was answered with %s", lastEval(w))
+	}
+}
+
+func TestCallPanic(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	w.Bind("crash", func() int { panic("boom") })
+	w.Bind("crashLater", func(ctx context.Context) error { panic("boom") })
+
+	for _, method := range []string{"crash", "crashLater"} {
+		_, err := w.Call(context.Background(), method)
+		var rpcErr *webview.RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != webview.RPCInternalError || rpcErr.Message != method+" panicked: boom" {
+			t.Errorf("%s(): got error %v, want an internal error", method, err)
+		}
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	w.Bind("echo", func(s string) string { return s })
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{
+			`{"jsonrpc":"2.0","id":1,"method":"echo","params":["hi"]}`,
+			`window._rpc.receive({"jsonrpc":"2.0","id":1,"result":"hi"});`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":2,"method":"echo","params":{"s":"hi"}}`,
+			`window._rpc.receive({"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"Invalid params","data":"params must be an array"}});`,
+		},
+		{
+			`{"id":3,"method":"echo","params":["hi"]}`,
+			`window._rpc.receive({"jsonrpc":"2.0","id":3,"error":{"code":-32600,"message":"Invalid Request"}});`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":4,"method":"missing"}`,
+			`window._rpc.receive({"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"Method not found","data":"missing"}});`,
+		},
+	}
+	for _, tt := range tests {
+		w.Invoke(tt.msg)
+		if got := lastEval(w); got != tt.want {
+			t.Errorf("Invoke(%s) evaluated\n%s\nwant\n%s", tt.msg, got, tt.want)
+		}
+	}
+
+	// Notifications are not answered.
+	n := len(w.EvalScripts)
+	w.Invoke(`{"jsonrpc":"2.0","method":"echo","params":["hi"]}`)
+	if len(w.EvalScripts) != n {
+		t.Errorf("notification was answered with %s", lastEval(w))
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	type key struct{}
+	w.Bind("value", func(ctx context.Context) (string, error) {
+		value, _ := ctx.Value(key{}).(string)
+		return value, nil
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "from Call")
+	res, err := w.Call(ctx, "value")
+	if err != nil || string(res) != `"from Call"` {
+		t.Errorf("value() = %s, %v, want the value of the context passed to Call", res, err)
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	w.Bind("wait", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+
+	// Cancelling the context of Call sends $/cancelRequest, like cancel() on
+	// the promise in JavaScript.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, err := w.Call(ctx, "wait"); err != context.Canceled {
+		t.Errorf("wait() returned %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context of the binding was not cancelled")
+	}
+}
+
+func TestNavigationCancelsCalls(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	started := make(chan struct{})
+	w.Bind("wait", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	go func() {
+		<-started
+		w.Navigation(&webview.NavigationEvent{Type: webview.NavigationStarted, URL: "https://example.com/"})
+	}()
+
+	// The page that made the call is gone, so the result is dropped rather
+	// than reported to the new page.
+	if _, err := w.Call(context.Background(), "wait"); err != webviewtest.ErrNavigated {
+		t.Errorf("wait() returned %v, want %v", err, webviewtest.ErrNavigated)
+	}
+}
+
+func TestUnbind(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	w.Bind("version", func() int { return 1 })
+	scripts := len(w.InitScripts)
+
+	if err := w.Unbind("version"); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.InitScripts) != scripts-1 {
+		t.Errorf("Unbind left %d init scripts, want %d", len(w.InitScripts), scripts-1)
+	}
+	if got, want := lastEval(w), `delete window["version"];`; got != want {
+		t.Errorf("Unbind evaluated %s, want %s", got, want)
+	}
+	_, err := w.Call(context.Background(), "version")
+	var rpcErr *webview.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != webview.RPCMethodNotFound {
+		t.Errorf("version() after Unbind returned %v, want RPCMethodNotFound", err)
+	}
+	if err := w.Unbind("version"); err == nil {
+		t.Error("Unbind of an unbound name succeeded")
+	}
+
+	// Rebinding replaces the previous function and its script.
+	w.Bind("version", func() int { return 1 })
+	w.Bind("version", func() int { return 2 })
+	if len(w.InitScripts) != scripts {
+		t.Errorf("rebinding left %d init scripts, want %d", len(w.InitScripts), scripts)
+	}
+	if res, err := w.Call(context.Background(), "version"); err != nil || string(res) != "2" {
+		t.Errorf("version() = %s, %v, want 2", res, err)
+	}
+}
+
+type api struct{}
+
+func (api) GetUser(id int) string { return "user" }
+func (api) HTTPGet() string       { return "get" }
+func (api) ID() int               { return 1 }
+func (api) URLs() []string        { return nil }
+func (api) private()              {}
+
+func TestBindObject(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	if err := w.BindObject("api", api{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for name := range w.Bindings() {
+		names = append(names, name)
+	}
+	for _, name := range []string{"api.getUser", "api.httpGet", "api.id", "api.urls"} {
+		if _, ok := w.Bindings()[name]; !ok {
+			t.Errorf("%s is not bound, got %v", name, names)
+		}
+	}
+	if len(names) != 4 {
+		t.Errorf("got bindings %v, want the 4 exported methods", names)
+	}
+	if res, err := w.Call(context.Background(), "api.getUser", 1); err != nil || string(res) != `"user"` {
+		t.Errorf("api.getUser(1) = %s, %v", res, err)
+	}
+
+	if err := w.Unbind("api"); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Bindings()) != 0 {
+		t.Errorf("Unbind left bindings %v", w.Bindings())
+	}
+}
+
+func TestBindObjectNil(t *testing.T) {
+	w := webviewtest.New()
+	defer w.Destroy()
+
+	var nilAPI *api
+	for _, obj := range []interface{}{nil, nilAPI} {
+		if err := w.BindObject("api", obj); err == nil {
+			t.Errorf("BindObject(%#v) succeeded", obj)
+		}
+	}
+}
+
+func TestEmit(t *testing.T) {
+	w := webviewtest.New()
+
+	var got json.RawMessage
+	off := w.On("saved", func(payload json.RawMessage) { got = payload })
+	w.EmitJS("saved", map[string]int{"id": 1})
+	if string(got) != `{"id":1}` {
+		t.Errorf("On handler received %s", got)
+	}
+	off()
+
+	if err := w.Emit("tick", 1); err != nil {
+		t.Fatal(err)
+	}
+	if want := `window.webview && window.webview._dispatch("tick", 1);`; lastEval(w) != want {
+		t.Errorf("Emit evaluated %s, want %s", lastEval(w), want)
+	}
+
+	// Nothing reaches the page once the webview is destroyed.
+	w.Destroy()
+	n := len(w.EvalScripts)
+	w.Emit("tick", 2)
+	w.Eval("1")
+	if len(w.EvalScripts) != n {
+		t.Errorf("evaluated %s after Destroy", lastEval(w))
+	}
+	if _, err := w.EvalResult(context.Background(), "1"); err != webview.ErrDestroyed {
+		t.Errorf("EvalResult after Destroy returned %v, want ErrDestroyed", err)
+	}
+}
+
+func lastEval(w *webviewtest.WebView) string {
+	if len(w.EvalScripts) == 0 {
+		return ""
+	}
+	return w.EvalScripts[len(w.EvalScripts)-1]
+}
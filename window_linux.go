@@ -0,0 +1,131 @@
+//go:build linux
+
+package webview
+
+import (
+	"github.com/mekkanized/go-webview/internal/linux/webkitgtk"
+)
+
+func (w *webview) OnWindowEvent(f func(e *WindowEvent)) (off func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.nextHandler++
+	id := w.nextHandler
+	w.windowHandlers[id] = f
+
+	return func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.windowHandlers, id)
+	}
+}
+
+// connectWindowEvents reports the signals of the window as window events.
+// GTK 4 replaced the GdkEvent based signals with properties.
+func (w *webview) connectWindowEvents() {
+	window := webkitgtk.GtkWidget(w.window)
+	if w.webkit.Library().ABI == webkitgtk.ABI60 {
+		w.connect(window, "close-request", func(window webkitgtk.GtkWindow, arg uintptr) bool {
+			return w.closeRequested()
+		})
+		w.connect(window, "notify::is-active", func(window webkitgtk.GtkWindow, pspec uintptr, arg uintptr) {
+			w.focusChanged(w.webkit.GtkWindowIsActive(window))
+		})
+		for _, property := range []string{"notify::default-width", "notify::default-height"} {
+			w.connect(window, property, func(window webkitgtk.GtkWindow, pspec uintptr, arg uintptr) {
+				width, height := w.webkit.GtkWindowGetSize(window)
+				w.configured(w.x, w.y, width, height)
+			})
+		}
+		for _, property := range []string{"notify::maximized", "notify::fullscreened"} {
+			w.connect(window, property, func(window webkitgtk.GtkWindow, pspec uintptr, arg uintptr) {
+				var state WindowState
+				if w.webkit.GtkWindowIsMaximized(window) {
+					state |= WindowStateMaximized
+				}
+				if w.webkit.GtkWindowIsFullscreen(window) {
+					state |= WindowStateFullscreen
+				}
+				w.emitWindowEvent(&WindowEvent{Type: WindowStateChanged, State: state})
+			})
+		}
+		return
+	}
+
+	w.connect(window, "delete-event", func(widget webkitgtk.GtkWidget, event uintptr, arg uintptr) bool {
+		return w.closeRequested()
+	})
+	w.connect(window, "configure-event", func(widget webkitgtk.GtkWidget, event uintptr, arg uintptr) bool {
+		e := w.webkit.CopyEventConfigure(event)
+		w.configured(int(e.X), int(e.Y), int(e.Width), int(e.Height))
+		return false
+	})
+	w.connect(window, "focus-in-event", func(widget webkitgtk.GtkWidget, event uintptr, arg uintptr) bool {
+		w.focusChanged(true)
+		return false
+	})
+	w.connect(window, "focus-out-event", func(widget webkitgtk.GtkWidget, event uintptr, arg uintptr) bool {
+		w.focusChanged(false)
+		return false
+	})
+	w.connect(window, "window-state-event", func(widget webkitgtk.GtkWidget, event uintptr, arg uintptr) bool {
+		e := w.webkit.CopyEventWindowState(event)
+		if e.ChangedMask&(webkitgtk.GDK_WINDOW_STATE_MAXIMIZED|webkitgtk.GDK_WINDOW_STATE_ICONIFIED|webkitgtk.GDK_WINDOW_STATE_FULLSCREEN) == 0 {
+			return false
+		}
+
+		var state WindowState
+		if e.NewWindowState&webkitgtk.GDK_WINDOW_STATE_MAXIMIZED != 0 {
+			state |= WindowStateMaximized
+		}
+		if e.NewWindowState&webkitgtk.GDK_WINDOW_STATE_ICONIFIED != 0 {
+			state |= WindowStateMinimized
+		}
+		if e.NewWindowState&webkitgtk.GDK_WINDOW_STATE_FULLSCREEN != 0 {
+			state |= WindowStateFullscreen
+		}
+		w.emitWindowEvent(&WindowEvent{Type: WindowStateChanged, State: state})
+		return false
+	})
+}
+
+// closeRequested returns true if the close request was cancelled.
+func (w *webview) closeRequested() bool {
+	e := &WindowEvent{Type: WindowCloseRequested}
+	w.emitWindowEvent(e)
+	return e.Cancel
+}
+
+// configured reports the moves and resizes since the last configure event.
+func (w *webview) configured(x, y, width, height int) {
+	if x != w.x || y != w.y {
+		w.x, w.y = x, y
+		w.emitWindowEvent(&WindowEvent{Type: WindowMoved, X: x, Y: y, Width: width, Height: height})
+	}
+	if width != w.width || height != w.height {
+		w.width, w.height = width, height
+		w.emitWindowEvent(&WindowEvent{Type: WindowResized, X: x, Y: y, Width: width, Height: height})
+	}
+}
+
+func (w *webview) focusChanged(focused bool) {
+	if focused {
+		w.emitWindowEvent(&WindowEvent{Type: WindowFocused})
+	} else {
+		w.emitWindowEvent(&WindowEvent{Type: WindowBlurred})
+	}
+}
+
+func (w *webview) emitWindowEvent(e *WindowEvent) {
+	w.mutex.Lock()
+	handlers := make([]func(*WindowEvent), 0, len(w.windowHandlers))
+	for _, f := range w.windowHandlers {
+		handlers = append(handlers, f)
+	}
+	w.mutex.Unlock()
+
+	for _, f := range handlers {
+		f(e)
+	}
+}